@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/amimof/huego"
+	"github.com/spf13/viper"
+)
+
+// a configured bridge which --all-bridges runs a command on
+type namedBridge struct {
+	Name     string
+	BridgeID string
+	Bridge   *huego.Bridge
+}
+
+// the result of running a command on one bridge
+type bridgeResult struct {
+	Lines []string
+	Err   error
+}
+
+// returns every bridge profile in the config file, or the single configured bridge when there are no profiles
+func loadNamedBridges() []namedBridge {
+	profiles, err := loadProfiles()
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	var bridges []namedBridge
+	for _, name := range profileNames(profiles) {
+		username, err := resolveUsername(profiles[name])
+		if err != nil {
+			fmt.Printf("ERROR: Profile \"%s\": %v\n", name, err)
+			os.Exit(1)
+		}
+		bridges = append(bridges, namedBridge{Name: name, BridgeID: profiles[name].BridgeID, Bridge: huego.New(profiles[name].Bridge, username)})
+	}
+
+	if len(bridges) < 1 {
+		bridges = append(bridges, namedBridge{Name: viper.GetString("bridge"), BridgeID: viper.GetString("bridgeid"), Bridge: huego.New(viper.GetString("bridge"), viper.GetString("username"))})
+	}
+
+	return bridges
+}
+
+// checks a bridge is the one its profile has an ID for
+func verifyNamedBridge(thisBridge namedBridge) error {
+	if thisBridge.BridgeID == "" {
+		return nil
+	}
+
+	identity, err := loadPublicConfig(thisBridge.Bridge.Host)
+	if err != nil {
+		return fmt.Errorf("bridge %s is not answering at %s: %v", thisBridge.BridgeID, thisBridge.Bridge.Host, err)
+	}
+	if !strings.EqualFold(identity.BridgeID, thisBridge.BridgeID) {
+		return fmt.Errorf("the bridge at %s is %s, not %s", thisBridge.Bridge.Host, identity.BridgeID, thisBridge.BridgeID)
+	}
+
+	return nil
+}
+
+// runs a command on every bridge at once, then prints each bridge's results in profile order prefixed with its name
+// under an optional tab separated header, returns false if the command failed on any bridge
+func forEachBridge(header string, command func(name string, thisBridge *huego.Bridge) ([]string, error)) bool {
+	bridges := loadNamedBridges()
+	results := make([]bridgeResult, len(bridges))
+
+	var wg sync.WaitGroup
+	for i, eachbridge := range bridges {
+		wg.Add(1)
+		go func(i int, eachbridge namedBridge) {
+			defer wg.Done()
+			if err := verifyNamedBridge(eachbridge); err != nil {
+				results[i] = bridgeResult{Err: err}
+				return
+			}
+			lines, err := command(eachbridge.Name, eachbridge.Bridge)
+			results[i] = bridgeResult{Lines: lines, Err: err}
+		}(i, eachbridge)
+	}
+	wg.Wait()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	if header != "" {
+		fmt.Fprintf(w, "Bridge\t%s\t\n", header)
+		fmt.Fprintf(w, "------\t%s\t\n", strings.Map(func(r rune) rune {
+			if r == '\t' {
+				return r
+			}
+			return '-'
+		}, header))
+	}
+	for i, eachbridge := range bridges {
+		for _, eachline := range results[i].Lines {
+			fmt.Fprintf(w, "%s\t%s\t\n", eachbridge.Name, eachline)
+		}
+	}
+	w.Flush()
+
+	// errors are printed after the results so they do not stretch the columns
+	ok := true
+	for i, eachbridge := range bridges {
+		if results[i].Err != nil {
+			fmt.Printf("ERROR: %s: %v\n", eachbridge.Name, results[i].Err)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// loads the lights of one bridge sorted by ID
+func loadBridgeLights(thisBridge *huego.Bridge) ([]huego.Light, error) {
+	lights, err := thisBridge.GetLights()
+	if err != nil {
+		return nil, fmt.Errorf("could not load lights from bridge %s: %v", thisBridge.Host, err)
+	}
+
+	sort.SliceStable(lights, func(i, j int) bool {
+		return lights[i].ID < lights[j].ID
+	})
+
+	return lights, nil
+}
+
+// describes a light the way --list and --listall do, with columns separated by tabs
+func describeLight(light huego.Light) string {
+	status := "off"
+	if light.State.On {
+		status = "on"
+	}
+
+	if viper.GetBool("listall") {
+		return fmt.Sprintf("%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", light.ID, status, light.Name, light.Type, light.ModelID, light.ManufacturerName, light.UniqueID, light.SwVersion, light.SwConfigID, light.ProductName)
+	}
+	return fmt.Sprintf("%d\t%s\t%s", light.ID, status, light.Name)
+}
+
+// lists the lights on every bridge
+func listAllBridges() bool {
+	header := "ID\tState\tName"
+	if viper.GetBool("listall") {
+		header = "ID\tState\tName\tType\tModelID\tManufacturor\tUniqueID\tSwVersion\tSwConfigID\tProductName"
+	}
+
+	return forEachBridge(header, func(name string, thisBridge *huego.Bridge) ([]string, error) {
+		lights, err := loadBridgeLights(thisBridge)
+		if err != nil {
+			return nil, err
+		}
+
+		var lines []string
+		for _, eachlight := range lights {
+			lines = append(lines, describeLight(eachlight))
+		}
+		return lines, nil
+	})
+}
+
+// runs --action on the light matching --light on every bridge, bridges without a matching light are skipped
+func actionAllBridges(selector string) bool {
+	var mu sync.Mutex
+	matched := 0
+
+	ok := forEachBridge("", func(name string, thisBridge *huego.Bridge) ([]string, error) {
+		lights, err := loadBridgeLights(thisBridge)
+		if err != nil {
+			return nil, err
+		}
+
+		var lines []string
+		for _, eachlight := range lights {
+			if !strings.EqualFold(eachlight.Name, selector) && strconv.Itoa(eachlight.ID) != selector {
+				continue
+			}
+
+			mu.Lock()
+			matched++
+			mu.Unlock()
+
+			light := eachlight
+			switch action {
+			case "on":
+				err = light.On()
+			case "off":
+				err = light.Off()
+			}
+			if err != nil {
+				return lines, fmt.Errorf("could not turn %s light \"%s\": %v", action, light.Name, err)
+			}
+
+			lightstate := "off"
+			if light.IsOn() {
+				lightstate = "on"
+			}
+			lines = append(lines, fmt.Sprintf("Light: \"%s\" is %s", light.Name, lightstate))
+		}
+		return lines, nil
+	})
+
+	if matched < 1 {
+		fmt.Printf("ERROR: \"--light %s\" is not a valid light name or light id on any bridge\n", selector)
+		return false
+	}
+
+	return ok
+}
+
+// inserts a bridge name before the extension of a file name, so backup.json becomes backup-office.json
+func bridgeFileName(file string, name string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "-" + name + ext
+}
+
+// backs up every bridge to its own file
+func backupAllBridges(outputFile string) bool {
+	if outputFile == "" {
+		fmt.Println("ERROR: --backup needs a file name ending in .json, .yaml or .yml")
+		os.Exit(1)
+	}
+
+	return forEachBridge("", func(name string, thisBridge *huego.Bridge) ([]string, error) {
+		backup, err := fetchBackup(thisBridge, viper.GetBool("includesecrets"))
+		if err != nil {
+			return nil, fmt.Errorf("could not back up bridge: %v", err)
+		}
+
+		file := bridgeFileName(outputFile, name)
+		if err := writeBackup(backup, file); err != nil {
+			return nil, fmt.Errorf("unable to save into the file %s: %v", file, err)
+		}
+
+		return []string{fmt.Sprintf("Backed up bridge %s (API %s) to %s", backup.BridgeID, backup.APIVersion, file)}, nil
+	})
+}
+
+// runs a command on every configured bridge
+func allBridges() {
+	var ok bool
+
+	switch {
+	case viper.IsSet("backup"):
+		ok = backupAllBridges(viper.GetString("backup"))
+	case viper.IsSet("action") && viper.IsSet("light"):
+		ok = actionAllBridges(viper.GetString("light"))
+	case viper.GetBool("list") || viper.GetBool("listall"):
+		ok = listAllBridges()
+	default:
+		fmt.Println("ERROR: --all-bridges works with --list, --listall, --backup and --light with --action")
+		os.Exit(1)
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/amimof/huego"
+)
+
+// automations created by huelight are tracked on the bridge as a resourcelink linking their rules and sensors,
+// the classid marks resourcelinks created by huelight so the resources it owns can be found again
+const automationClassID uint16 = 4646
+
+// the maximum length of rule, sensor and resourcelink names on the bridge
+const bridgeNameLength int = 32
+
+// the description of a resourcelink marks which kind of automation it holds
+func automationDescription(kind string) string {
+	return fmt.Sprintf("%s %s", applicationName, kind)
+}
+
+// shortens a name so that name and suffix fit within the bridge name length
+func automationResourceName(name string, suffix string) string {
+	available := bridgeNameLength - len(suffix) - 1
+	if len(name) > available {
+		name = name[:available]
+	}
+	return strings.TrimSpace(name + " " + suffix)
+}
+
+// loads all resourcelinks created by huelight for a kind of automation
+func loadAutomations(kind string) []*huego.Resourcelink {
+	var found []*huego.Resourcelink
+	for _, eachlink := range loadResourcelinks() {
+		if eachlink.ClassID == automationClassID && eachlink.Description == automationDescription(kind) {
+			found = append(found, eachlink)
+		}
+	}
+	return found
+}
+
+// find an automation created by huelight by its name
+func findAutomation(kind string, name string) (*huego.Resourcelink, bool) {
+	for _, eachlink := range loadAutomations(kind) {
+		if strings.EqualFold(eachlink.Name, name) {
+			return eachlink, true
+		}
+	}
+	return nil, false
+}
+
+// creates the status sensor used by an automation to hold state between rules, returning its ID
+func createStatusSensor(name string) (int, error) {
+	return createCLIPSensor(name, "CLIPGenericStatus")
+}
+
+// creates the rules of an automation and the resourcelink tracking them along with its sensors,
+// removing everything it created when any of it fails
+func applyAutomation(kind string, name string, sensors []int, rules []*huego.Rule) error {
+	links := sensorLinks(sensors)
+
+	for _, eachrule := range rules {
+		resp, err := myBridge.CreateRule(eachrule)
+		if err != nil {
+			// remove what was created so a failed apply does not leave stray rules behind
+			removeLinkedResources(links)
+			return fmt.Errorf("could not create rule \"%s\": %v", eachrule.Name, err)
+		}
+		links = append(links, fmt.Sprintf("/rules/%v", resp.Success["id"]))
+		fmt.Printf("Created rule \"%s\" with id %v\n", eachrule.Name, resp.Success["id"])
+	}
+
+	newlink := &huego.Resourcelink{
+		Name:        name,
+		Description: automationDescription(kind),
+		Type:        "Link",
+		ClassID:     automationClassID,
+		Links:       links,
+	}
+
+	resp, err := myBridge.CreateResourcelink(newlink)
+	if err != nil {
+		removeLinkedResources(links)
+		return fmt.Errorf("could not create resourcelink \"%s\": %v", name, err)
+	}
+
+	fmt.Printf("Created %s \"%s\" with %d rules, tracked by resourcelink %v\n", kind, name, len(rules), resp.Success["id"])
+	return nil
+}
+
+// removes an automation created by huelight, along with its rules and sensors
+func removeAutomation(link *huego.Resourcelink) {
+	removeLinkedResources(link.Links)
+
+	if err := myBridge.DeleteResourcelink(link.ID); err != nil {
+		fmt.Printf("ERROR: Could not delete resourcelink \"%s\": %v\n", link.Name, err)
+		return
+	}
+	fmt.Printf("Deleted resourcelink \"%s\" (%d)\n", link.Name, link.ID)
+}
+
+// deletes each resource in a list of resourcelink addresses, such as /rules/3
+func removeLinkedResources(links []string) {
+	// rules are deleted before the sensors they refer to
+	for _, kind := range []string{"rules", "schedules", "sensors", "scenes"} {
+		for _, eachlink := range links {
+			parts := strings.Split(strings.Trim(eachlink, "/"), "/")
+			if len(parts) != 2 || parts[0] != kind {
+				continue
+			}
+
+			var err error
+			switch kind {
+			case "scenes":
+				err = myBridge.DeleteScene(parts[1])
+			default:
+				id, converr := strconv.Atoi(parts[1])
+				if converr != nil {
+					continue
+				}
+				switch kind {
+				case "rules":
+					err = myBridge.DeleteRule(id)
+				case "schedules":
+					err = myBridge.DeleteSchedule(id)
+				case "sensors":
+					// only sensors created through the api are deleted, never physical devices
+					sensor, geterr := myBridge.GetSensor(id)
+					if geterr == nil && !strings.HasPrefix(sensor.Type, "CLIP") {
+						fmt.Printf("WARN: Not deleting %s, \"%s\" is a %s\n", eachlink, sensor.Name, sensor.Type)
+						continue
+					}
+					err = myBridge.DeleteSensor(id)
+				}
+			}
+
+			if err != nil {
+				fmt.Printf("WARN: Could not delete %s: %v\n", eachlink, err)
+				continue
+			}
+			fmt.Printf("Deleted %s\n", eachlink)
+		}
+	}
+}
+
+// returns the resourcelink addresses of sensors
+func sensorLinks(sensors []int) []string {
+	var links []string
+	for _, eachsensor := range sensors {
+		links = append(links, fmt.Sprintf("/sensors/%d", eachsensor))
+	}
+	return links
+}
+
+// display the automations of a kind created by huelight along with their rules
+func listAutomations(kind string) {
+	loadGroups()
+	loadSensors()
+	automations := loadAutomations(kind)
+	rules := loadRules()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t\n", "Name", "Link", "Rule")
+	fmt.Fprintf(w, "%s\t%s\t%s\t\n", "----", "----", "----")
+
+	for _, eachautomation := range automations {
+		for _, eachlink := range eachautomation.Links {
+			rendered := ""
+			var ruleid int
+			if _, err := fmt.Sscanf(eachlink, "/rules/%d", &ruleid); err == nil {
+				rendered = "missing"
+				if rule, found := findRule(rules, strconv.Itoa(ruleid)); found {
+					rendered = renderRule(rule)
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t\n", eachautomation.Name, eachlink, rendered)
+		}
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of %s automations found: %d\n", kind, len(automations))
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/amimof/huego"
+	"github.com/spf13/viper"
+)
+
+const backupArchiveVersion int = 1
+
+// the resource types saved in a backup, in the order they are fetched
+var backupResources = []string{"lights", "groups", "scenes", "schedules", "rules", "sensors", "resourcelinks"}
+
+// a full backup of a bridge, resources are kept as the bridge returns them so nothing is lost
+type bridgeBackup struct {
+	Application string                            `json:"application" yaml:"application"`
+	Version     int                               `json:"version" yaml:"version"`
+	Created     string                            `json:"created" yaml:"created"`
+	BridgeID    string                            `json:"bridgeid" yaml:"bridgeid"`
+	APIVersion  string                            `json:"apiversion" yaml:"apiversion"`
+	SwVersion   string                            `json:"swversion" yaml:"swversion"`
+	Redacted    bool                              `json:"redacted" yaml:"redacted"`
+	Config      map[string]interface{}            `json:"config" yaml:"config"`
+	Resources   map[string]map[string]interface{} `json:"resources" yaml:"resources"`
+}
+
+// fetches everything from a bridge in to a backup
+func fetchBackup(thisBridge *huego.Bridge, includeSecrets bool) (*bridgeBackup, error) {
+	backup := &bridgeBackup{
+		Application: applicationName,
+		Version:     backupArchiveVersion,
+		Created:     time.Now().UTC().Format(time.RFC3339),
+		Resources:   map[string]map[string]interface{}{},
+	}
+
+	result, err := bridgeRequest(thisBridge, "GET", "config", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not load config: %v", err)
+	}
+	if err := json.Unmarshal(result, &backup.Config); err != nil {
+		return nil, fmt.Errorf("could not read config: %v", err)
+	}
+
+	backup.BridgeID = fmt.Sprint(backup.Config["bridgeid"])
+	backup.APIVersion = fmt.Sprint(backup.Config["apiversion"])
+	backup.SwVersion = fmt.Sprint(backup.Config["swversion"])
+
+	for _, kind := range backupResources {
+		result, err := bridgeRequest(thisBridge, "GET", kind, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not load %s: %v", kind, err)
+		}
+
+		resources := map[string]interface{}{}
+		if err := json.Unmarshal(result, &resources); err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", kind, err)
+		}
+		backup.Resources[kind] = resources
+	}
+
+	// lightstates are only returned when requesting a single scene
+	for id := range backup.Resources["scenes"] {
+		result, err := bridgeRequest(thisBridge, "GET", "scenes/"+id, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not load scene %s: %v", id, err)
+		}
+
+		var scene map[string]interface{}
+		if err := json.Unmarshal(result, &scene); err != nil {
+			return nil, fmt.Errorf("could not read scene %s: %v", id, err)
+		}
+		backup.Resources["scenes"][id] = scene
+	}
+
+	if !includeSecrets {
+		if err := redactBackup(backup); err != nil {
+			return nil, err
+		}
+	}
+
+	return backup, nil
+}
+
+// the placeholder a username is redacted to, derived from the username so it stays the same
+// whichever other users are added or removed and backups can be compared
+func redactedUsername(username string) string {
+	sum := sha256.Sum256([]byte(username))
+	return "redacted-" + hex.EncodeToString(sum[:6])
+}
+
+// replaces whitelist usernames, which are used as api keys, wherever they appear in a backup
+func redactBackup(backup *bridgeBackup) error {
+	whitelist, _ := backup.Config["whitelist"].(map[string]interface{})
+
+	var usernames []string
+	for k := range whitelist {
+		usernames = append(usernames, k)
+	}
+	// longest first so a username containing another is replaced whole
+	sort.SliceStable(usernames, func(i, j int) bool {
+		if len(usernames[i]) == len(usernames[j]) {
+			return usernames[i] < usernames[j]
+		}
+		return len(usernames[i]) > len(usernames[j])
+	})
+
+	data, err := json.Marshal(backup)
+	if err != nil {
+		return err
+	}
+
+	// usernames also appear as scene, rule and resourcelink owners and in schedule addresses,
+	// so each is replaced by the same placeholder throughout to keep those references matching
+	redacted := string(data)
+	for _, eachusername := range usernames {
+		redacted = strings.ReplaceAll(redacted, eachusername, redactedUsername(eachusername))
+	}
+
+	var result bridgeBackup
+	if err := json.Unmarshal([]byte(redacted), &result); err != nil {
+		return err
+	}
+
+	*backup = result
+	backup.Redacted = true
+	return nil
+}
+
+// saves a backup as json or yaml depending on the file extension
+func writeBackup(backup *bridgeBackup, outputFile string) error {
+	var data []byte
+	var err error
+
+	if strings.EqualFold(filepath.Ext(outputFile), ".json") {
+		data, err = json.MarshalIndent(backup, "", "  ")
+	} else {
+		data, err = yaml.Marshal(backup)
+	}
+	if err != nil {
+		return err
+	}
+
+	// backups can contain api keys so are only readable by their owner
+	return ioutil.WriteFile(outputFile, data, 0600)
+}
+
+// reads a json or yaml backup
+func readBackup(inputFile string) (*bridgeBackup, error) {
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var backup bridgeBackup
+	if strings.EqualFold(filepath.Ext(inputFile), ".json") {
+		err = json.Unmarshal(data, &backup)
+	} else {
+		err = yaml.Unmarshal(data, &backup)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("\"%s\" is not a valid backup: %v", inputFile, err)
+	}
+
+	if backup.Version < 1 || backup.Version > backupArchiveVersion {
+		return nil, fmt.Errorf("\"%s\" has backup version %d, supported version is %d", inputFile, backup.Version, backupArchiveVersion)
+	}
+
+	return &backup, nil
+}
+
+// backs up the bridge to a file
+func backupBridge(outputFile string) {
+	if outputFile == "" {
+		fmt.Println("ERROR: --backup needs a file name ending in .json, .yaml or .yml")
+		os.Exit(1)
+	}
+
+	backup, err := fetchBackup(myBridge, viper.GetBool("includesecrets"))
+	if err != nil {
+		fmt.Printf("ERROR: Could not back up bridge: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := writeBackup(backup, outputFile); err != nil {
+		fmt.Printf("ERROR: Unable to save into the file: %s\n", outputFile)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Backed up bridge %s (API %s) to %s\n", backup.BridgeID, backup.APIVersion, outputFile)
+	for _, kind := range backupResources {
+		fmt.Printf("  %-14s %d\n", kind, len(backup.Resources[kind]))
+	}
+	if backup.Redacted {
+		fmt.Println("Whitelist usernames were redacted, use --includesecrets to keep them")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// builds a backup with a whitelist of usernames, each owning a scene
+func testBackup(usernames ...string) *bridgeBackup {
+	whitelist := map[string]interface{}{}
+	scenes := map[string]interface{}{}
+	for _, eachusername := range usernames {
+		whitelist[eachusername] = map[string]interface{}{"name": "app#" + eachusername[:4]}
+		scenes["scene-"+eachusername[:4]] = map[string]interface{}{"owner": eachusername}
+	}
+	return &bridgeBackup{
+		Config:    map[string]interface{}{"whitelist": whitelist},
+		Resources: map[string]map[string]interface{}{"scenes": scenes},
+	}
+}
+
+func TestRedactBackup(t *testing.T) {
+	kept := "aaaaBBBBccccDDDDeeeeFFFFggggHHHHiiiiJJJJ"
+	removed := "zzzzYYYYxxxxWWWWvvvvUUUUttttSSSSrrrrQQQQ"
+
+	before := testBackup(kept, removed)
+	after := testBackup(kept)
+	for _, eachbackup := range []*bridgeBackup{before, after} {
+		if err := redactBackup(eachbackup); err != nil {
+			t.Fatalf("redactBackup unexpected error: %v", err)
+		}
+		if !eachbackup.Redacted {
+			t.Error("redactBackup did not mark the backup as redacted")
+		}
+	}
+
+	placeholder := redactedUsername(kept)
+	if !strings.HasPrefix(placeholder, "redacted-") || strings.Contains(placeholder, kept) {
+		t.Errorf("redactedUsername(%q) = %q", kept, placeholder)
+	}
+
+	// a user removed between backups leaves the placeholders of the others unchanged
+	for name, eachbackup := range map[string]*bridgeBackup{"before": before, "after": after} {
+		whitelist := eachbackup.Config["whitelist"].(map[string]interface{})
+		if _, found := whitelist[placeholder]; !found {
+			t.Errorf("%s: whitelist %v has no %s", name, whitelist, placeholder)
+		}
+		owner := eachbackup.Resources["scenes"]["scene-aaaa"].(map[string]interface{})["owner"]
+		if owner != placeholder {
+			t.Errorf("%s: scene owner = %v, want %s", name, owner, placeholder)
+		}
+	}
+
+	if redactedUsername(kept) == redactedUsername(removed) {
+		t.Errorf("usernames %q and %q redact to the same placeholder", kept, removed)
+	}
+}
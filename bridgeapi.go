@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/amimof/huego"
+)
+
+var bridgeClient = &http.Client{Timeout: 10 * time.Second}
+
+// an error returned by the bridge api
+type bridgeError struct {
+	Type        int    `json:"type"`
+	Address     string `json:"address"`
+	Description string `json:"description"`
+}
+
+func (e *bridgeError) Error() string {
+	return fmt.Sprintf("ERROR %d [%s]: \"%s\"", e.Type, e.Address, e.Description)
+}
+
+// sends a request to the bridge api for calls huego does not support or marshals incorrectly, apiPath is relative to /api/<username>/
+func bridgeRequest(thisBridge *huego.Bridge, method string, apiPath string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, bridgeURL(thisBridge, apiPath), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := bridgeClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	result, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the bridge reports errors inside a successful http response
+	var responses []struct {
+		Error *bridgeError `json:"error"`
+	}
+	if json.Unmarshal(result, &responses) == nil {
+		for _, eachresponse := range responses {
+			if eachresponse.Error != nil {
+				return result, eachresponse.Error
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// returns the full url of a path on the bridge api
+func bridgeURL(thisBridge *huego.Bridge, apiPath string) string {
+	return fmt.Sprintf("%s/api/%s/%s", bridgeHostURL(thisBridge.Host), thisBridge.User, strings.TrimPrefix(apiPath, "/"))
+}
+
+// returns the url of a bridge host, which may be a bare address
+func bridgeHostURL(host string) string {
+	host = strings.TrimSuffix(host, "/")
+	if !strings.HasPrefix(strings.ToLower(host), "http://") && !strings.HasPrefix(strings.ToLower(host), "https://") {
+		host = "http://" + host
+	}
+	return host
+}
+
+// creates a resource such as a group or scene on the bridge, returning the ID the bridge gave it
+func createBridgeResource(thisBridge *huego.Bridge, kind string, body interface{}) (string, error) {
+	result, err := bridgeRequest(thisBridge, "POST", kind, body)
+	if err != nil {
+		return "", err
+	}
+
+	var responses []struct {
+		Success map[string]interface{} `json:"success"`
+	}
+	if err := json.Unmarshal(result, &responses); err != nil {
+		return "", err
+	}
+
+	for _, eachresponse := range responses {
+		if id, ok := eachresponse.Success["id"]; ok {
+			return fmt.Sprint(id), nil
+		}
+	}
+
+	return "", fmt.Errorf("bridge did not return an id for the new %s", strings.TrimSuffix(kind, "s"))
+}
+
+// the details a bridge shares without a username
+type bridgePublicConfig struct {
+	Name       string `json:"name"`
+	BridgeID   string `json:"bridgeid"`
+	APIVersion string `json:"apiversion"`
+	SwVersion  string `json:"swversion"`
+	ModelID    string `json:"modelid"`
+	Mac        string `json:"mac"`
+}
+
+// loads the public configuration of a bridge, used to identify it before logging in
+func loadPublicConfig(host string) (*bridgePublicConfig, error) {
+	res, err := bridgeClient.Get(bridgeHostURL(host) + "/api/config")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var config bridgePublicConfig
+	if err := json.NewDecoder(res.Body).Decode(&config); err != nil {
+		return nil, err
+	}
+	if config.BridgeID == "" {
+		return nil, fmt.Errorf("%s did not identify itself as a Hue bridge", host)
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/amimof/huego"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// looks up the ID of a bridge, from discovery when it was found there or by asking the bridge
+func lookupBridgeID(host string) string {
+	for _, eachbridge := range foundBridges {
+		if strings.EqualFold(eachbridge.Host, host) && eachbridge.ID != "" {
+			return strings.ToUpper(eachbridge.ID)
+		}
+	}
+
+	if identity, err := loadPublicConfig(host); err == nil {
+		return strings.ToUpper(identity.BridgeID)
+	}
+
+	return ""
+}
+
+// finds a bridge by ID on the network, returning its address
+func rediscoverBridge(bridgeID string) (string, bool) {
+	discoverBridges()
+
+	for _, eachbridge := range foundBridges {
+		if !strings.EqualFold(eachbridge.ID, bridgeID) {
+			continue
+		}
+		// check the bridge answers as itself at the address it was found at
+		if identity, err := loadPublicConfig(eachbridge.Host); err == nil && strings.EqualFold(identity.BridgeID, bridgeID) {
+			return eachbridge.Host, true
+		}
+	}
+
+	return "", false
+}
+
+// connects to the configured bridge, checking it is the bridge the config file expects and finding it again by ID
+// when it no longer answers at its configured address
+func connectBridge() {
+	host := viper.GetString("bridge")
+	expected := strings.ToUpper(viper.GetString("bridgeid"))
+
+	// a bridge passed on the command line is not the one the config file has an ID for
+	if pflag.CommandLine.Changed("bridge") {
+		expected = ""
+	}
+
+	identity, err := loadPublicConfig(host)
+	switch {
+	case err == nil && (expected == "" || strings.EqualFold(identity.BridgeID, expected)):
+		myBridge = huego.New(host, "")
+		// store selected bridge ID because struct loses it once logged in
+		myBridgeID = identity.BridgeID
+
+		// without a saved ID the bridge is known by the ID it answered with, saving it rewrites the config file
+		// so is only done when agreed to
+		if expected == "" && !pflag.CommandLine.Changed("bridge") {
+			fmt.Printf("WARN: The config file has no bridge ID, save %s so the bridge can be verified and found if its address changes? [y/n]: ", identity.BridgeID)
+			if offerPrompt() {
+				saveBridgeIdentity(host, identity.BridgeID)
+			}
+		}
+		return
+	case expected == "":
+		fmt.Printf("ERROR: Could not connect to bridge \"%s\": %v\n", host, err)
+		os.Exit(1)
+	case err == nil:
+		fmt.Printf("WARN: The bridge at %s is %s, not %s from the config file, searching for %s\n", host, identity.BridgeID, expected, expected)
+	default:
+		fmt.Printf("WARN: Bridge %s is not answering at %s, searching for it\n", expected, host)
+	}
+
+	newhost, found := rediscoverBridge(expected)
+	if !found {
+		fmt.Printf("ERROR: Could not find bridge %s on the network\n", expected)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Found bridge %s at %s\n", expected, newhost)
+	fmt.Printf("Update the config file to use %s? [y/n]: ", newhost)
+	if offerPrompt() {
+		saveBridgeIdentity(newhost, expected)
+	}
+
+	viper.Set("bridge", newhost)
+	myBridge = huego.New(newhost, "")
+	myBridgeID = expected
+}
+
+// saves the address and ID of the bridge in use to the config file
+func saveBridgeIdentity(host string, bridgeID string) {
+	err := updateConfigProfile(func(profile *bridgeProfile) {
+		profile.Bridge = host
+		profile.BridgeID = strings.ToUpper(bridgeID)
+	})
+	if err != nil {
+		fmt.Printf("ERROR: Unable to update the config file: %v\n", err)
+		return
+	}
+	fmt.Printf("Saved bridge %s at %s to %s\n", strings.ToUpper(bridgeID), host, viper.ConfigFileUsed())
+}
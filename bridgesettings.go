@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amimof/huego"
+	"github.com/spf13/viper"
+)
+
+// how often and for how long the bridge is read back after changing its settings
+const bridgeSettingsPollInterval time.Duration = 2 * time.Second
+const bridgeSettingsTimeout time.Duration = time.Minute
+
+// settings which change how the bridge is reached on the network
+var networkSettings = map[string]bool{
+	"dhcp":      true,
+	"ipaddress": true,
+	"netmask":   true,
+	"gateway":   true,
+}
+
+// zigbee channels supported by the bridge
+var zigbeeChannels = []int{11, 15, 20, 25}
+
+// a change to a bridge setting, shown to the user before it is applied and verified afterwards
+type bridgeSettingChange struct {
+	Key     string
+	Current interface{}
+	New     interface{}
+}
+
+// loads the list of timezones the bridge supports
+func loadBridgeTimezones() []string {
+	result, err := bridgeRequest(myBridge, "GET", "capabilities/timezones", nil)
+	if err != nil {
+		fmt.Printf("ERROR: Could not load timezones from bridge: %v\n", err)
+		os.Exit(1)
+	}
+
+	var timezones struct {
+		Values []string `json:"values"`
+	}
+	if err := json.Unmarshal(result, &timezones); err != nil {
+		fmt.Printf("ERROR: Could not read timezones from bridge: %v\n", err)
+		os.Exit(1)
+	}
+
+	return timezones.Values
+}
+
+// collects the bridge setting changes passed on the command line, validating each one
+func collectBridgeSettings(current *huego.Config) []bridgeSettingChange {
+	var changes []bridgeSettingChange
+
+	if viper.IsSet("setname") {
+		name := viper.GetString("setname")
+		if len(name) < 4 || len(name) > 16 {
+			fmt.Println("ERROR: --setname must be between 4 and 16 characters")
+			os.Exit(1)
+		}
+		changes = append(changes, bridgeSettingChange{Key: "name", Current: current.Name, New: name})
+	}
+
+	if viper.IsSet("settimezone") {
+		timezone := viper.GetString("settimezone")
+		valid := false
+		for _, eachtimezone := range loadBridgeTimezones() {
+			if eachtimezone == timezone {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			fmt.Printf("ERROR: \"--settimezone %s\" is not a timezone supported by the bridge, for example Europe/London\n", timezone)
+			os.Exit(1)
+		}
+		changes = append(changes, bridgeSettingChange{Key: "timezone", Current: current.TimeZone, New: timezone})
+	}
+
+	if viper.IsSet("setzigbeechannel") {
+		channel := viper.GetInt("setzigbeechannel")
+		valid := false
+		for _, eachchannel := range zigbeeChannels {
+			if eachchannel == channel {
+				valid = true
+			}
+		}
+		if !valid {
+			fmt.Printf("ERROR: --setzigbeechannel must be one of %v\n", zigbeeChannels)
+			os.Exit(1)
+		}
+		fmt.Println("WARN: Changing the Zigbee channel makes every light and sensor move to the new channel, some may become unreachable and need to be power cycled or searched for again")
+		changes = append(changes, bridgeSettingChange{Key: "zigbeechannel", Current: current.ZigbeeChannel, New: channel})
+	}
+
+	if viper.IsSet("dhcp") {
+		changes = append(changes, bridgeSettingChange{Key: "dhcp", Current: current.Dhcp, New: viper.GetBool("dhcp")})
+	}
+
+	for _, eachsetting := range []struct {
+		flag    string
+		key     string
+		current string
+	}{
+		{"ipaddress", "ipaddress", current.IPAddress},
+		{"netmask", "netmask", current.NetMask},
+		{"gateway", "gateway", current.Gateway},
+	} {
+		if !viper.IsSet(eachsetting.flag) {
+			continue
+		}
+		address := viper.GetString(eachsetting.flag)
+		if ip := net.ParseIP(address); ip == nil || ip.To4() == nil {
+			fmt.Printf("ERROR: \"--%s %s\" is not a valid IPv4 address\n", eachsetting.flag, address)
+			os.Exit(1)
+		}
+		changes = append(changes, bridgeSettingChange{Key: eachsetting.key, Current: eachsetting.current, New: address})
+	}
+
+	// a static address needs dhcp turned off, otherwise the bridge ignores it
+	if viper.IsSet("ipaddress") && !viper.IsSet("dhcp") && current.Dhcp {
+		fmt.Println("ERROR: Setting a static --ipaddress needs --dhcp=false")
+		os.Exit(1)
+	}
+
+	return changes
+}
+
+// changes bridge settings after confirmation, then reads them back to verify they were applied
+func updateBridgeSettings() {
+	current, err := myBridge.GetConfig()
+	checkErr(err)
+
+	changes := collectBridgeSettings(current)
+	if len(changes) < 1 {
+		fmt.Println("ERROR: No bridge settings to change")
+		os.Exit(1)
+	}
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t\n", "Setting", "Current", "New")
+	fmt.Fprintf(w, "%s\t%s\t%s\t\n", "-------", "-------", "---")
+
+	body := map[string]interface{}{}
+	network := false
+	for _, eachchange := range changes {
+		fmt.Fprintf(w, "%s\t%v\t%v\t\n", eachchange.Key, eachchange.Current, eachchange.New)
+		body[eachchange.Key] = eachchange.New
+		if networkSettings[eachchange.Key] {
+			network = true
+		}
+	}
+	w.Flush()
+
+	if network {
+		fmt.Println("\nWARN: Changing network settings may make the bridge unreachable at its current address")
+	}
+
+	fmt.Printf("\nApply these changes to bridge \"%s\"? [y/n]: ", current.Name)
+	if !yesNoPrompt() {
+		fmt.Println("WARN: Aborting bridge settings change")
+		return
+	}
+
+	// huego.UpdateConfig sends every field, including read only ones, so only the changes are sent
+	if _, err := bridgeRequest(myBridge, "PUT", "config", body); err != nil {
+		fmt.Printf("ERROR: Could not update bridge settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	verifyBridgeSettings(changes)
+}
+
+// does the bridge configuration show a change
+func settingApplied(config map[string]interface{}, change bridgeSettingChange) bool {
+	return strings.EqualFold(fmt.Sprint(config[change.Key]), fmt.Sprint(change.New))
+}
+
+// does the bridge configuration show every change
+func settingsApplied(config map[string]interface{}, changes []bridgeSettingChange) bool {
+	for _, eachchange := range changes {
+		if !settingApplied(config, eachchange) {
+			return false
+		}
+	}
+	return true
+}
+
+// reads the bridge configuration back and checks each change was applied
+func verifyBridgeSettings(changes []bridgeSettingChange) {
+	readback := myBridge
+	dhcp := false
+	for _, eachchange := range changes {
+		if eachchange.Key == "ipaddress" {
+			// a new static address means the bridge now answers there
+			readback = huego.New(fmt.Sprint(eachchange.New), myBridge.User)
+		}
+		if eachchange.Key == "dhcp" && eachchange.New == true {
+			fmt.Println("WARN: The bridge will now get its address by DHCP, run --findbridges to find its new address")
+			dhcp = true
+		}
+	}
+
+	// the address the bridge gets by DHCP is not known, so its network settings are not read back
+	// and the bridge is found again by its ID to check the rest
+	if dhcp {
+		var remaining []bridgeSettingChange
+		for _, eachchange := range changes {
+			if !networkSettings[eachchange.Key] {
+				remaining = append(remaining, eachchange)
+			}
+		}
+		changes = remaining
+		if len(changes) < 1 {
+			return
+		}
+
+		host, found := rediscoverBridge(myBridgeID)
+		if !found {
+			fmt.Printf("WARN: Could not find bridge %s again, the other changes were not verified\n", myBridgeID)
+			return
+		}
+		readback = huego.New(host, myBridge.User)
+	}
+
+	// network changes take a few seconds and a zigbee channel change applies in the background,
+	// so the bridge is read until every change shows or the timeout passes
+	var config map[string]interface{}
+	var err error
+	waiting := false
+	deadline := time.Now().Add(bridgeSettingsTimeout)
+	for {
+		var result []byte
+		var readconfig map[string]interface{}
+		result, err = bridgeRequest(readback, "GET", "config", nil)
+		if err == nil {
+			err = json.Unmarshal(result, &readconfig)
+		}
+		if err == nil {
+			config = readconfig
+			if settingsApplied(config, changes) {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		if !waiting {
+			fmt.Printf("Waiting up to %s for the bridge to apply the changes\n", bridgeSettingsTimeout)
+			waiting = true
+		}
+		time.Sleep(bridgeSettingsPollInterval)
+	}
+
+	if config == nil {
+		fmt.Printf("ERROR: Could not read back bridge settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	for _, eachchange := range changes {
+		if settingApplied(config, eachchange) {
+			fmt.Printf("Verified %s is %v\n", eachchange.Key, eachchange.New)
+			continue
+		}
+		fmt.Printf("ERROR: %s is %v, expected %v\n", eachchange.Key, config[eachchange.Key], eachchange.New)
+		failed++
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/amimof/huego"
+)
+
+// the state attribute holding the value of each kind of clip generic sensor
+var clipSensorAttributes = map[string]string{
+	"CLIPGenericFlag":   "flag",
+	"CLIPGenericStatus": "status",
+}
+
+// the uniqueid of a clip sensor, a short hash of its name so it is distinct per name
+// and fits the 32 character limit of the bridge however long the name is
+func clipSensorUniqueID(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return applicationName + "-" + hex.EncodeToString(sum[:6])
+}
+
+// creates a clip generic sensor, returning its ID
+func createCLIPSensor(name string, sensorType string) (int, error) {
+	if len(name) < 1 || len(name) > bridgeNameLength {
+		return 0, fmt.Errorf("sensor name \"%s\" must be between 1 and %d characters", name, bridgeNameLength)
+	}
+
+	newsensor := &huego.Sensor{
+		Name:             name,
+		Type:             sensorType,
+		ModelID:          applicationName,
+		ManufacturerName: applicationName,
+		SwVersion:        applicationVersion,
+		UniqueID:         clipSensorUniqueID(name),
+	}
+
+	resp, err := myBridge.CreateSensor(newsensor)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(fmt.Sprint(resp.Success["id"]))
+}
+
+// find a clip generic flag or status sensor when given its name or ID
+func findCLIPSensor(selector string) huego.Sensor {
+	sensorid, found := getSensorIDFromName(selector)
+	if !found {
+		fmt.Printf("ERROR: \"%s\" is not a valid sensor name or sensor id\n", selector)
+		os.Exit(1)
+	}
+
+	sensor, _ := getSensor(sensorid)
+	if _, ok := clipSensorAttributes[sensor.Type]; !ok {
+		fmt.Printf("ERROR: Sensor \"%s\" is a %s, not a CLIPGenericFlag or CLIPGenericStatus sensor\n", sensor.Name, sensor.Type)
+		os.Exit(1)
+	}
+
+	return sensor
+}
+
+// display a list of all clip generic flag and status sensors
+func listCLIPSensors() {
+	loadSensors()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "ID", "Name", "Type", "Value", "LastUpdated")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "--", "----", "----", "-----", "-----------")
+
+	found := 0
+	for _, eachsensor := range loadedSensors {
+		attribute, ok := clipSensorAttributes[eachsensor.Type]
+		if !ok {
+			continue
+		}
+		found++
+		fmt.Fprintf(w, "%d\t%s\t%s\t%v\t%v\t\n", eachsensor.ID, eachsensor.Name, eachsensor.Type, eachsensor.State[attribute], eachsensor.State["lastupdated"])
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of flag and status sensors found: %d\n", found)
+}
+
+// creates a clip generic flag or status sensor
+func createCLIPSensorCommand(name string, sensorType string) {
+	sensorid, err := createCLIPSensor(name, sensorType)
+	if err != nil {
+		fmt.Printf("ERROR: Could not create sensor \"%s\": %v\n", name, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created %s sensor \"%s\" with id %d\n", sensorType, name, sensorid)
+}
+
+// sets the value of a clip generic flag or status sensor
+func setCLIPSensor(selector string, value string) {
+	loadSensors()
+	sensor := findCLIPSensor(selector)
+	attribute := clipSensorAttributes[sensor.Type]
+
+	var newvalue interface{}
+	switch attribute {
+	case "flag":
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			fmt.Printf("ERROR: Sensor \"%s\" is a flag, --value must be true or false\n", sensor.Name)
+			os.Exit(1)
+		}
+		newvalue = flag
+	case "status":
+		status, err := strconv.Atoi(value)
+		if err != nil {
+			fmt.Printf("ERROR: Sensor \"%s\" is a status, --value must be a whole number\n", sensor.Name)
+			os.Exit(1)
+		}
+		newvalue = status
+	}
+
+	_, err := bridgeRequest(myBridge, "PUT", fmt.Sprintf("sensors/%d/state", sensor.ID), map[string]interface{}{attribute: newvalue})
+	if err != nil {
+		fmt.Printf("ERROR: Could not set sensor \"%s\": %v\n", sensor.Name, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Sensor \"%s\" %s is now %v\n", sensor.Name, attribute, newvalue)
+}
+
+// deletes a clip generic flag or status sensor
+func deleteCLIPSensor(selector string) {
+	loadSensors()
+	sensor := findCLIPSensor(selector)
+
+	fmt.Printf("Delete sensor \"%s\" (%d)? [y/n]: ", sensor.Name, sensor.ID)
+	if !yesNoPrompt() {
+		fmt.Println("WARN: Aborting sensor delete")
+		return
+	}
+
+	checkErr(myBridge.DeleteSensor(sensor.ID))
+	fmt.Printf("Deleted sensor \"%s\"\n", sensor.Name)
+}
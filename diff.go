@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// fields which change on their own, such as timestamps, and would hide real changes
+var diffIgnoredFields = map[string]bool{
+	"UTC":            true,
+	"localtime":      true,
+	"last use date":  true,
+	"lastupdated":    true,
+	"lasttriggered":  true,
+	"timestriggered": true,
+	"lastchange":     true,
+}
+
+// a field which differs between two versions of a resource
+type fieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// how one resource differs between two backups
+type resourceChange struct {
+	ID     string
+	Name   string
+	Change string
+	Fields []fieldChange
+}
+
+// flattens nested fields in to paths like state/bri, lists are kept whole so they read as one value
+func flattenFields(prefix string, value interface{}, fields map[string]string) {
+	if nested, ok := value.(map[string]interface{}); ok {
+		for k, v := range nested {
+			if diffIgnoredFields[k] {
+				continue
+			}
+			path := k
+			if prefix != "" {
+				path = prefix + "/" + k
+			}
+			flattenFields(path, v, fields)
+		}
+		return
+	}
+
+	if text, ok := value.(string); ok {
+		fields[prefix] = text
+		return
+	}
+
+	data, _ := json.Marshal(value)
+	fields[prefix] = string(data)
+}
+
+// compares two versions of a resource field by field
+func diffFields(before interface{}, after interface{}) []fieldChange {
+	oldfields := map[string]string{}
+	newfields := map[string]string{}
+	flattenFields("", before, oldfields)
+	flattenFields("", after, newfields)
+
+	paths := map[string]bool{}
+	for k := range oldfields {
+		paths[k] = true
+	}
+	for k := range newfields {
+		paths[k] = true
+	}
+
+	var changes []fieldChange
+	for path := range paths {
+		oldvalue, inold := oldfields[path]
+		newvalue, innew := newfields[path]
+		if inold && innew && oldvalue == newvalue {
+			continue
+		}
+		if !inold {
+			oldvalue = "(none)"
+		}
+		if !innew {
+			newvalue = "(none)"
+		}
+		changes = append(changes, fieldChange{Field: path, Old: oldvalue, New: newvalue})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes
+}
+
+// returns the name of a resource kept in a backup
+func resourceName(resource interface{}) string {
+	if fields, ok := resource.(map[string]interface{}); ok {
+		if name, ok := fields["name"]; ok {
+			return fmt.Sprint(name)
+		}
+	}
+	return ""
+}
+
+// compares one resource type between two backups
+func diffResources(before map[string]interface{}, after map[string]interface{}) []resourceChange {
+	var changes []resourceChange
+
+	for id, oldresource := range before {
+		newresource, found := after[id]
+		if !found {
+			changes = append(changes, resourceChange{ID: id, Name: resourceName(oldresource), Change: "removed"})
+			continue
+		}
+		if fields := diffFields(oldresource, newresource); len(fields) > 0 {
+			changes = append(changes, resourceChange{ID: id, Name: resourceName(newresource), Change: "changed", Fields: fields})
+		}
+	}
+
+	for id, newresource := range after {
+		if _, found := before[id]; !found {
+			changes = append(changes, resourceChange{ID: id, Name: resourceName(newresource), Change: "added"})
+		}
+	}
+
+	// sort numerically where the ids are numbers, scene ids are not
+	sort.SliceStable(changes, func(i, j int) bool {
+		if len(changes[i].ID) == len(changes[j].ID) {
+			return changes[i].ID < changes[j].ID
+		}
+		return len(changes[i].ID) < len(changes[j].ID)
+	})
+
+	return changes
+}
+
+// display the differences between two backups, returns true if there are any
+func displayBackupDiff(before *bridgeBackup, after *bridgeBackup, oldName string, newName string) bool {
+	fmt.Printf("--- %s (bridge %s, %s)\n", oldName, before.BridgeID, before.Created)
+	fmt.Printf("+++ %s (bridge %s, %s)\n", newName, after.BridgeID, after.Created)
+
+	if before.BridgeID != after.BridgeID {
+		fmt.Println("WARN: These backups are from different bridges, resources are compared by ID")
+	}
+
+	sections := []string{"config"}
+	sections = append(sections, backupResources...)
+
+	total := 0
+	for _, kind := range sections {
+		var changes []resourceChange
+		if kind == "config" {
+			if fields := diffFields(before.Config, after.Config); len(fields) > 0 {
+				changes = append(changes, resourceChange{ID: "config", Name: fmt.Sprint(after.Config["name"]), Change: "changed", Fields: fields})
+			}
+		} else {
+			changes = diffResources(before.Resources[kind], after.Resources[kind])
+		}
+
+		if len(changes) < 1 {
+			continue
+		}
+		total += len(changes)
+
+		fmt.Printf("\n%s\n", kind)
+		for _, eachchange := range changes {
+			switch eachchange.Change {
+			case "added":
+				fmt.Printf("  + %s \"%s\"\n", eachchange.ID, eachchange.Name)
+			case "removed":
+				fmt.Printf("  - %s \"%s\"\n", eachchange.ID, eachchange.Name)
+			default:
+				fmt.Printf("  ~ %s \"%s\"\n", eachchange.ID, eachchange.Name)
+				for _, eachfield := range eachchange.Fields {
+					fmt.Printf("      %s: %s -> %s\n", eachfield.Field, eachfield.Old, eachfield.New)
+				}
+			}
+		}
+	}
+
+	if total < 1 {
+		fmt.Println("\nNo differences found")
+		return false
+	}
+
+	fmt.Printf("\nNumber of resources that differ: %d\n", total)
+	return true
+}
+
+// compares a backup with another backup, or with the bridge when newFile is empty,
+// exiting 1 when there are differences like diff does
+func diffBackups(oldFile string, newFile string) {
+	before, err := readBackup(oldFile)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	var after *bridgeBackup
+	newName := newFile
+	if newFile == "" {
+		// the bridge is redacted the same way as the backup so usernames do not show as changes
+		after, err = fetchBackup(myBridge, !before.Redacted)
+		if err != nil {
+			fmt.Printf("ERROR: Could not read bridge: %v\n", err)
+			os.Exit(1)
+		}
+		newName = "bridge " + myBridge.Host
+	} else {
+		after, err = readBackup(newFile)
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if before.Redacted != after.Redacted {
+		fmt.Println("WARN: Only one side has redacted usernames, so whitelist usernames will show as changed")
+	}
+
+	if displayBackupDiff(before, after, oldFile, newName) {
+		os.Exit(1)
+	}
+}
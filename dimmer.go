@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/amimof/huego"
+)
+
+const dimmerKind string = "dimmer"
+
+// a dimmer switch button mapping read from yaml
+//
+//	name: hall-dimmer
+//	switch: Hall dimmer
+//	group: Hall
+//	buttons:
+//	  - button: on
+//	    press: short
+//	    action: scenecycle
+//	    scenes: [Bright, Relax, Nightlight]
+//	  - button: up
+//	    press: hold
+//	    action: brighten
+//	  - button: off
+//	    press: short
+//	    action: off
+type dimmerMapping struct {
+	Name    string         `yaml:"name"`
+	Switch  string         `yaml:"switch"`
+	Group   string         `yaml:"group"`
+	Buttons []dimmerButton `yaml:"buttons"`
+}
+
+type dimmerButton struct {
+	Button string   `yaml:"button"`
+	Press  string   `yaml:"press"`
+	Action string   `yaml:"action"`
+	Scene  string   `yaml:"scene,omitempty"`
+	Scenes []string `yaml:"scenes,omitempty"`
+	Step   int      `yaml:"step,omitempty"`
+}
+
+// button numbers of the hue dimmer switch
+var dimmerButtons = map[string]int{
+	"on":   1,
+	"up":   2,
+	"down": 3,
+	"off":  4,
+}
+
+// the last digits of a buttonevent for each kind of press
+var dimmerPresses = map[string]int{
+	"initial": 0,
+	"hold":    1,
+	"short":   2,
+	"long":    3,
+}
+
+// the default brightness change for brighten and dim
+const dimmerDefaultStep int = 30
+
+// reads a dimmer switch mapping from a yaml file
+func readDimmerMapping(inputFile string) dimmerMapping {
+	yamlData, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		fmt.Printf("ERROR: Unable to read file: %s\n", inputFile)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var mapping dimmerMapping
+	if err := yaml.Unmarshal(yamlData, &mapping); err != nil {
+		fmt.Printf("ERROR: File \"%s\" is not a valid dimmer mapping: %v\n", inputFile, err)
+		os.Exit(1)
+	}
+
+	if mapping.Name == "" {
+		mapping.Name = mapping.Switch
+	}
+
+	if len(mapping.Name) > bridgeNameLength {
+		fmt.Printf("ERROR: Dimmer mapping name \"%s\" must be %d characters or less\n", mapping.Name, bridgeNameLength)
+		os.Exit(1)
+	}
+
+	return mapping
+}
+
+// returns the buttonevent for a button and press, such as 1002 for a short press of on
+func dimmerButtonEvent(button dimmerButton) (int, error) {
+	number, ok := dimmerButtons[strings.ToLower(button.Button)]
+	if !ok {
+		parsed, err := strconv.Atoi(button.Button)
+		if err != nil || parsed < 1 || parsed > 4 {
+			return 0, fmt.Errorf("button \"%s\" must be on, up, down, off or 1-4", button.Button)
+		}
+		number = parsed
+	}
+
+	press := strings.ToLower(button.Press)
+	if press == "" {
+		press = "short"
+	}
+
+	code, ok := dimmerPresses[press]
+	if !ok {
+		return 0, fmt.Errorf("press \"%s\" must be short, long, hold or initial", button.Press)
+	}
+
+	return number*1000 + code, nil
+}
+
+// compiles a dimmer switch mapping in to bridge rules, creating the status sensors scene cycles need
+func compileDimmerMapping(mapping dimmerMapping) ([]int, []*huego.Rule, error) {
+	switchid, found := getSensorIDFromName(mapping.Switch)
+	if !found {
+		return nil, nil, fmt.Errorf("\"%s\" is not a valid sensor name or sensor id", mapping.Switch)
+	}
+
+	if dimmer, _ := getSensor(switchid); dimmer.Type != "ZLLSwitch" {
+		return nil, nil, fmt.Errorf("sensor \"%s\" is a %s, not a dimmer switch", dimmer.Name, dimmer.Type)
+	}
+
+	groupid, found := getGroupIDFromName(mapping.Group)
+	if !found {
+		return nil, nil, fmt.Errorf("\"%s\" is not a valid group name or group id", mapping.Group)
+	}
+
+	scenes := loadScenes()
+	var sensors []int
+	var rules []*huego.Rule
+
+	groupAddress := fmt.Sprintf("/groups/%d/action", groupid)
+
+	for _, eachbutton := range mapping.Buttons {
+		buttonevent, err := dimmerButtonEvent(eachbutton)
+		if err != nil {
+			return sensors, nil, err
+		}
+
+		// every rule fires on this button event, lastupdated changing stops a repeated state from firing again
+		conditions := []*huego.Condition{
+			{Address: fmt.Sprintf("/sensors/%d/state/buttonevent", switchid), Operator: "eq", Value: strconv.Itoa(buttonevent)},
+			{Address: fmt.Sprintf("/sensors/%d/state/lastupdated", switchid), Operator: "dx"},
+		}
+
+		rulename := automationResourceName(mapping.Name, fmt.Sprintf("%d", buttonevent))
+		step := eachbutton.Step
+		if step == 0 {
+			step = dimmerDefaultStep
+		}
+
+		switch strings.ToLower(eachbutton.Action) {
+		case "on", "off":
+			rules = append(rules, &huego.Rule{
+				Name:       rulename,
+				Conditions: conditions,
+				Actions:    []*huego.RuleAction{{Address: groupAddress, Method: "PUT", Body: map[string]interface{}{"on": strings.EqualFold(eachbutton.Action, "on")}}},
+			})
+
+		case "brighten", "dim":
+			if strings.EqualFold(eachbutton.Action, "dim") {
+				step = -step
+			}
+			rules = append(rules, &huego.Rule{
+				Name:       rulename,
+				Conditions: conditions,
+				Actions:    []*huego.RuleAction{{Address: groupAddress, Method: "PUT", Body: map[string]interface{}{"bri_inc": step, "transitiontime": 9}}},
+			})
+
+		case "scene":
+			sceneid, err := dimmerSceneID(scenes, eachbutton.Scene)
+			if err != nil {
+				return sensors, nil, err
+			}
+			rules = append(rules, &huego.Rule{
+				Name:       rulename,
+				Conditions: conditions,
+				Actions:    []*huego.RuleAction{{Address: groupAddress, Method: "PUT", Body: map[string]interface{}{"scene": sceneid}}},
+			})
+
+		case "scenecycle":
+			if len(eachbutton.Scenes) < 2 {
+				return sensors, nil, fmt.Errorf("scenecycle on button %s needs at least two scenes", eachbutton.Button)
+			}
+
+			// the status sensor remembers which scene in the cycle comes next
+			statusid, err := createStatusSensor(automationResourceName(mapping.Name, fmt.Sprintf("%d cycle", buttonevent)))
+			if err != nil {
+				return sensors, nil, fmt.Errorf("could not create status sensor: %v", err)
+			}
+			sensors = append(sensors, statusid)
+
+			for i, eachscene := range eachbutton.Scenes {
+				sceneid, err := dimmerSceneID(scenes, eachscene)
+				if err != nil {
+					return sensors, nil, err
+				}
+
+				cycleconditions := append([]*huego.Condition{}, conditions...)
+				cycleconditions = append(cycleconditions, &huego.Condition{Address: fmt.Sprintf("/sensors/%d/state/status", statusid), Operator: "eq", Value: strconv.Itoa(i)})
+
+				rules = append(rules, &huego.Rule{
+					Name:       automationResourceName(mapping.Name, fmt.Sprintf("%d scene %d", buttonevent, i)),
+					Conditions: cycleconditions,
+					Actions: []*huego.RuleAction{
+						{Address: groupAddress, Method: "PUT", Body: map[string]interface{}{"scene": sceneid}},
+						{Address: fmt.Sprintf("/sensors/%d/state", statusid), Method: "PUT", Body: map[string]interface{}{"status": (i + 1) % len(eachbutton.Scenes)}},
+					},
+				})
+			}
+
+		default:
+			return sensors, nil, fmt.Errorf("action \"%s\" on button %s must be on, off, brighten, dim, scene or scenecycle", eachbutton.Action, eachbutton.Button)
+		}
+	}
+
+	// turning the group off restarts every scene cycle from its first scene
+	for _, eachrule := range rules {
+		for _, eachaction := range eachrule.Actions {
+			if body, ok := eachaction.Body.(map[string]interface{}); ok && eachaction.Address == groupAddress && body["on"] == false {
+				for _, eachsensor := range sensors {
+					eachrule.Actions = append(eachrule.Actions, &huego.RuleAction{Address: fmt.Sprintf("/sensors/%d/state", eachsensor), Method: "PUT", Body: map[string]interface{}{"status": 0}})
+				}
+				break
+			}
+		}
+	}
+
+	return sensors, rules, nil
+}
+
+// find the ID of a scene by name or ID
+func dimmerSceneID(scenes []huego.Scene, selector string) (string, error) {
+	found := findScenes(scenes, selector)
+	if len(found) != 1 {
+		return "", fmt.Errorf("scene \"%s\" must match exactly one scene, found %d", selector, len(found))
+	}
+	return found[0].ID, nil
+}
+
+// compiles a dimmer switch mapping and applies it to the bridge, replacing the rules from any previous apply
+func applyDimmerMapping(inputFile string) {
+	loadGroups()
+	loadSensors()
+
+	mapping := readDimmerMapping(inputFile)
+
+	// the existing mapping is only removed once its replacement is working, so a bad mapping never leaves the switch dead
+	existing, replacing := findAutomation(dimmerKind, mapping.Name)
+
+	sensors, rules, err := compileDimmerMapping(mapping)
+	if err != nil {
+		fmt.Printf("ERROR: Dimmer mapping \"%s\": %v\n", mapping.Name, err)
+		removeLinkedResources(sensorLinks(sensors))
+		os.Exit(1)
+	}
+
+	if err := applyAutomation(dimmerKind, mapping.Name, sensors, rules); err != nil {
+		fmt.Printf("ERROR: Dimmer mapping \"%s\": %v\n", mapping.Name, err)
+		if replacing {
+			fmt.Println("The existing dimmer mapping was left in place")
+		}
+		os.Exit(1)
+	}
+
+	if replacing {
+		fmt.Printf("Removing the dimmer mapping \"%s\" it replaces\n", mapping.Name)
+		removeAutomation(existing)
+	}
+}
+
+// removes a dimmer switch mapping along with its rules and sensors
+func removeDimmerMapping(name string) {
+	existing, found := findAutomation(dimmerKind, name)
+	if !found {
+		fmt.Printf("ERROR: \"%s\" is not a dimmer mapping created by %s\n", name, applicationName)
+		os.Exit(1)
+	}
+	removeAutomation(existing)
+}
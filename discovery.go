@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"huelights/discovery"
+)
+
+// discover all bridges
+func discoverBridges() {
+	var errs []error
+	foundBridges, errs = discovery.Bridges(viper.GetDuration("discoverytimeout"))
+
+	// a discovery method failing only matters if nothing else found a bridge
+	if len(foundBridges) < 1 {
+		for _, eacherr := range errs {
+			fmt.Printf("WARN: Discovery by %v\n", eacherr)
+		}
+	}
+}
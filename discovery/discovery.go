@@ -0,0 +1,378 @@
+// Package discovery finds Hue bridges on the local network with mDNS and SSDP,
+// and with the Hue discovery service when online.
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/amimof/huego"
+)
+
+// SSDPAddress and MDNSAddress are the multicast addresses bridges answer discovery on
+const SSDPAddress string = "239.255.255.250:1900"
+const MDNSAddress string = "224.0.0.251:5353"
+
+// the mDNS service bridges advertise
+const hueService string = "_hue._tcp.local"
+
+// dns record types used by mDNS discovery
+const (
+	dnsTypeA   uint16 = 1
+	dnsTypePTR uint16 = 12
+	dnsTypeTXT uint16 = 16
+	dnsTypeSRV uint16 = 33
+)
+
+// a record from a dns message, data is kept with the message because names inside it can point back in to the message
+type dnsRecord struct {
+	Name       string
+	Type       uint16
+	Data       []byte
+	DataOffset int
+}
+
+// sends a discovery request to a udp address and passes each reply to handle until the timeout
+func udpSearch(target string, request []byte, timeout time.Duration, handle func(reply []byte, from *net.UDPAddr)) error {
+	address, err := net.ResolveUDPAddr("udp4", target)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteToUDP(request, address); err != nil {
+		return err
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var neterr net.Error
+			if errors.As(err, &neterr) && neterr.Timeout() {
+				return nil
+			}
+			return err
+		}
+		handle(append([]byte(nil), buf[:n]...), from)
+	}
+}
+
+// SSDPSearch finds bridges by sending an SSDP M-SEARCH to target, bridges identify themselves with a hue-bridgeid header
+func SSDPSearch(target string, timeout time.Duration) ([]huego.Bridge, error) {
+	request := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + SSDPAddress + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: " + fmt.Sprint(int(timeout.Seconds())+1) + "\r\n" +
+		"ST: ssdp:all\r\n\r\n"
+
+	var bridges []huego.Bridge
+	err := udpSearch(target, []byte(request), timeout, func(reply []byte, from *net.UDPAddr) {
+		res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(reply)), nil)
+		if err != nil {
+			return
+		}
+		res.Body.Close()
+
+		bridgeid := res.Header.Get("hue-bridgeid")
+		if bridgeid == "" {
+			return
+		}
+
+		host := from.IP.String()
+		if location, err := url.Parse(res.Header.Get("Location")); err == nil && location.Hostname() != "" {
+			host = location.Hostname()
+		}
+
+		bridges = append(bridges, huego.Bridge{Host: host, ID: bridgeid})
+	})
+
+	return bridges, err
+}
+
+// encodes a dns name such as _hue._tcp.local
+func encodeDNSName(name string) []byte {
+	var encoded []byte
+	for _, eachlabel := range strings.Split(strings.Trim(name, "."), ".") {
+		encoded = append(encoded, byte(len(eachlabel)))
+		encoded = append(encoded, eachlabel...)
+	}
+	return append(encoded, 0)
+}
+
+// builds an mDNS query for the PTR records of a service, asking for a unicast reply
+func buildMDNSQuery(service string) []byte {
+	query := make([]byte, 12)
+	binary.BigEndian.PutUint16(query[4:], 1)
+	query = append(query, encodeDNSName(service)...)
+	question := make([]byte, 4)
+	binary.BigEndian.PutUint16(question, dnsTypePTR)
+	// class IN with the top bit set to ask for a unicast response
+	binary.BigEndian.PutUint16(question[2:], 0x8001)
+	return append(query, question...)
+}
+
+// reads a possibly compressed dns name at offset, returning the name and the offset after it
+func readDNSName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+
+	// each pointer must go backwards, which stops pointer loops
+	for limit := offset; ; {
+		if offset >= len(msg) {
+			return "", 0, errors.New("dns name runs past end of message")
+		}
+
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(msg) {
+				return "", 0, errors.New("dns name pointer runs past end of message")
+			}
+			pointer := int(binary.BigEndian.Uint16(msg[offset:]) & 0x3fff)
+			if pointer >= limit {
+				return "", 0, errors.New("dns name pointer does not point backwards")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = pointer
+			limit = pointer
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errors.New("dns label runs past end of message")
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// parses every record in the answer, authority and additional sections of a dns message
+func parseDNSMessage(msg []byte) ([]dnsRecord, error) {
+	if len(msg) < 12 {
+		return nil, errors.New("dns message is too short")
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	records := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+
+	offset := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4
+	}
+
+	var parsed []dnsRecord
+	for i := 0; i < records; i++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(msg) {
+			return nil, errors.New("dns record runs past end of message")
+		}
+
+		recordtype := binary.BigEndian.Uint16(msg[next:])
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		if start+length > len(msg) {
+			return nil, errors.New("dns record data runs past end of message")
+		}
+
+		parsed = append(parsed, dnsRecord{Name: strings.ToLower(name), Type: recordtype, Data: msg[start : start+length], DataOffset: start})
+		offset = start + length
+	}
+
+	return parsed, nil
+}
+
+// reads the key=value strings of a TXT record
+func parseTXTRecord(data []byte) map[string]string {
+	values := map[string]string{}
+	for len(data) > 0 {
+		length := int(data[0])
+		if 1+length > len(data) {
+			break
+		}
+		pair := strings.SplitN(string(data[1:1+length]), "=", 2)
+		if len(pair) == 2 {
+			values[strings.ToLower(pair[0])] = pair[1]
+		}
+		data = data[1+length:]
+	}
+	return values
+}
+
+// finds bridges from an mDNS reply, linking each advertised instance to its bridgeid and address
+func parseMDNSReply(msg []byte, from *net.UDPAddr) ([]huego.Bridge, error) {
+	records, err := parseDNSMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []string
+	targets := map[string]string{}
+	bridgeids := map[string]string{}
+	addresses := map[string]string{}
+
+	for _, eachrecord := range records {
+		switch eachrecord.Type {
+		case dnsTypePTR:
+			if eachrecord.Name != hueService {
+				continue
+			}
+			instance, _, err := readDNSName(msg, eachrecord.DataOffset)
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, strings.ToLower(instance))
+		case dnsTypeSRV:
+			if len(eachrecord.Data) < 7 {
+				continue
+			}
+			// priority, weight and port come before the target
+			target, _, err := readDNSName(msg, eachrecord.DataOffset+6)
+			if err != nil {
+				return nil, err
+			}
+			targets[eachrecord.Name] = strings.ToLower(target)
+		case dnsTypeTXT:
+			bridgeids[eachrecord.Name] = parseTXTRecord(eachrecord.Data)["bridgeid"]
+		case dnsTypeA:
+			if len(eachrecord.Data) == 4 {
+				addresses[eachrecord.Name] = net.IP(eachrecord.Data).String()
+			}
+		}
+	}
+
+	var bridges []huego.Bridge
+	for _, eachinstance := range instances {
+		bridgeid := bridgeids[eachinstance]
+		if bridgeid == "" {
+			continue
+		}
+
+		host := from.IP.String()
+		if address, ok := addresses[targets[eachinstance]]; ok {
+			host = address
+		}
+
+		bridges = append(bridges, huego.Bridge{Host: host, ID: bridgeid})
+	}
+
+	return bridges, nil
+}
+
+// MDNSQuery finds bridges advertising the _hue._tcp service by sending an mDNS query to target
+func MDNSQuery(target string, timeout time.Duration) ([]huego.Bridge, error) {
+	var bridges []huego.Bridge
+	err := udpSearch(target, buildMDNSQuery(hueService), timeout, func(reply []byte, from *net.UDPAddr) {
+		found, err := parseMDNSReply(reply, from)
+		if err != nil {
+			return
+		}
+		bridges = append(bridges, found...)
+	})
+
+	return bridges, err
+}
+
+// Merge merges bridges found by each discovery method, keeping the first found of each bridge ID
+func Merge(found ...[]huego.Bridge) []huego.Bridge {
+	seen := map[string]bool{}
+	var merged []huego.Bridge
+
+	for _, eachlist := range found {
+		for _, eachbridge := range eachlist {
+			// bridge IDs are upper case over SSDP and lower case over mDNS and the discovery service
+			eachbridge.ID = strings.ToUpper(eachbridge.ID)
+			key := eachbridge.ID
+			if key == "" {
+				key = eachbridge.Host
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, eachbridge)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].ID < merged[j].ID
+	})
+
+	return merged
+}
+
+// Bridges discovers bridges with mDNS, SSDP and the Hue discovery service at once, returning the merged bridges
+// and an error for each method which failed
+func Bridges(timeout time.Duration) ([]huego.Bridge, []error) {
+	methods := []struct {
+		name     string
+		discover func() ([]huego.Bridge, error)
+	}{
+		{"mDNS", func() ([]huego.Bridge, error) { return MDNSQuery(MDNSAddress, timeout) }},
+		{"SSDP", func() ([]huego.Bridge, error) { return SSDPSearch(SSDPAddress, timeout) }},
+		{"discovery service", func() ([]huego.Bridge, error) {
+			// the discovery service gets the same time as the local methods, an offline network never answers
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return huego.DiscoverAllContext(ctx)
+		}},
+	}
+
+	results := make([][]huego.Bridge, len(methods))
+	errs := make([]error, len(methods))
+
+	var wg sync.WaitGroup
+	for i, eachmethod := range methods {
+		wg.Add(1)
+		go func(i int, name string, discover func() ([]huego.Bridge, error)) {
+			defer wg.Done()
+			results[i], errs[i] = discover()
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %v", name, errs[i])
+			}
+		}(i, eachmethod.name, eachmethod.discover)
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, eacherr := range errs {
+		if eacherr != nil {
+			failed = append(failed, eacherr)
+		}
+	}
+
+	return Merge(results...), failed
+}
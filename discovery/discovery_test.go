@@ -0,0 +1,205 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amimof/huego"
+)
+
+const testTimeout = 300 * time.Millisecond
+
+// starts a local udp responder which answers each request with replies built from it,
+// returning the responder address and a channel receiving each request
+func startResponder(t *testing.T, reply func(request []byte) [][]byte) (string, <-chan []byte) {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("could not start responder: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	requests := make(chan []byte, 10)
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			request := append([]byte(nil), buf[:n]...)
+			requests <- request
+			for _, eachreply := range reply(request) {
+				conn.WriteToUDP(eachreply, from)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String(), requests
+}
+
+func ssdpReply(headers ...string) []byte {
+	return []byte("HTTP/1.1 200 OK\r\n" + strings.Join(headers, "\r\n") + "\r\n\r\n")
+}
+
+func TestSSDPSearch(t *testing.T) {
+	address, requests := startResponder(t, func(request []byte) [][]byte {
+		return [][]byte{
+			ssdpReply("CACHE-CONTROL: max-age=100", "LOCATION: http://192.168.1.20:80/description.xml", "SERVER: Hue/1.0 UPnP/1.0 IpBridge/1.53.0", "hue-bridgeid: 001788FFFE09A206", "ST: upnp:rootdevice"),
+			// other upnp devices answer too and are ignored
+			ssdpReply("LOCATION: http://192.168.1.30:8080/dev.xml", "ST: upnp:rootdevice"),
+			[]byte("not http at all"),
+		}
+	})
+
+	bridges, err := SSDPSearch(address, testTimeout)
+	if err != nil {
+		t.Fatalf("SSDPSearch returned error: %v", err)
+	}
+
+	request := string(<-requests)
+	if !strings.HasPrefix(request, "M-SEARCH * HTTP/1.1\r\n") || !strings.Contains(request, "MAN: \"ssdp:discover\"") {
+		t.Errorf("request is not an M-SEARCH: %q", request)
+	}
+
+	if len(bridges) != 1 {
+		t.Fatalf("found %d bridges, expected 1: %v", len(bridges), bridges)
+	}
+	if bridges[0].ID != "001788FFFE09A206" || bridges[0].Host != "192.168.1.20" {
+		t.Errorf("found bridge %s at %s, expected 001788FFFE09A206 at 192.168.1.20", bridges[0].ID, bridges[0].Host)
+	}
+}
+
+func TestSSDPSearchWithoutLocation(t *testing.T) {
+	address, _ := startResponder(t, func(request []byte) [][]byte {
+		return [][]byte{ssdpReply("hue-bridgeid: 001788FFFE09A206")}
+	})
+
+	bridges, err := SSDPSearch(address, testTimeout)
+	if err != nil {
+		t.Fatalf("SSDPSearch returned error: %v", err)
+	}
+	if len(bridges) != 1 || bridges[0].Host != "127.0.0.1" {
+		t.Errorf("expected the bridge at the address it replied from, found %v", bridges)
+	}
+}
+
+func TestSearchTimeout(t *testing.T) {
+	address, _ := startResponder(t, func(request []byte) [][]byte {
+		return nil
+	})
+
+	started := time.Now()
+	bridges, err := SSDPSearch(address, testTimeout)
+	if err != nil {
+		t.Fatalf("SSDPSearch returned error: %v", err)
+	}
+	if len(bridges) != 0 {
+		t.Errorf("found %d bridges from a silent responder", len(bridges))
+	}
+	if elapsed := time.Since(started); elapsed < testTimeout || elapsed > 5*testTimeout {
+		t.Errorf("search took %s, expected about %s", elapsed, testTimeout)
+	}
+}
+
+// builds a dns resource record
+func dnsRR(name []byte, recordtype uint16, data []byte) []byte {
+	record := append([]byte(nil), name...)
+	fixed := make([]byte, 10)
+	binary.BigEndian.PutUint16(fixed, recordtype)
+	binary.BigEndian.PutUint16(fixed[2:], 0x8001)
+	binary.BigEndian.PutUint32(fixed[4:], 120)
+	binary.BigEndian.PutUint16(fixed[8:], uint16(len(data)))
+	record = append(record, fixed...)
+	return append(record, data...)
+}
+
+// builds an mDNS reply advertising a bridge, using a compression pointer back to the service name
+func mdnsReply(instance string, bridgeid string, ip net.IP) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[2:], 0x8400)
+	binary.BigEndian.PutUint16(msg[6:], 1)
+	binary.BigEndian.PutUint16(msg[10:], 3)
+
+	// the service name is written once and pointed to afterwards
+	serviceoffset := len(msg)
+	servicename := encodeDNSName(hueService)
+	pointer := []byte{0xc0 | byte(serviceoffset>>8), byte(serviceoffset)}
+	instancename := append([]byte{byte(len(instance))}, instance...)
+	instancename = append(instancename, pointer...)
+
+	msg = append(msg, dnsRR(servicename, dnsTypePTR, instancename)...)
+
+	hostname := encodeDNSName("hue-bridge.local")
+	srv := []byte{0, 0, 0, 0, 0, 80}
+	msg = append(msg, dnsRR(instancename, dnsTypeSRV, append(srv, hostname...))...)
+
+	txt := "bridgeid=" + bridgeid
+	msg = append(msg, dnsRR(instancename, dnsTypeTXT, append([]byte{byte(len(txt))}, txt...))...)
+
+	return append(msg, dnsRR(hostname, dnsTypeA, ip.To4())...)
+}
+
+func TestMDNSQuery(t *testing.T) {
+	address, requests := startResponder(t, func(request []byte) [][]byte {
+		return [][]byte{
+			mdnsReply("Hue Bridge - 09A206", "001788fffe09a206", net.IPv4(192, 168, 1, 20)),
+			// truncated and unrelated replies are ignored
+			{0, 0, 0},
+		}
+	})
+
+	bridges, err := MDNSQuery(address, testTimeout)
+	if err != nil {
+		t.Fatalf("MDNSQuery returned error: %v", err)
+	}
+
+	request := <-requests
+	if binary.BigEndian.Uint16(request[4:]) != 1 {
+		t.Fatalf("query should have one question")
+	}
+	name, next, err := readDNSName(request, 12)
+	if err != nil || name != hueService {
+		t.Errorf("query asks for %q (%v), expected %s", name, err, hueService)
+	}
+	if binary.BigEndian.Uint16(request[next:]) != dnsTypePTR || binary.BigEndian.Uint16(request[next+2:]) != 0x8001 {
+		t.Errorf("query should ask for PTR records with a unicast reply")
+	}
+
+	if len(bridges) != 1 {
+		t.Fatalf("found %d bridges, expected 1: %v", len(bridges), bridges)
+	}
+	if bridges[0].ID != "001788fffe09a206" || bridges[0].Host != "192.168.1.20" {
+		t.Errorf("found bridge %s at %s, expected 001788fffe09a206 at 192.168.1.20", bridges[0].ID, bridges[0].Host)
+	}
+}
+
+func TestReadDNSNamePointerLoop(t *testing.T) {
+	// a pointer to itself must not loop forever
+	msg := append(make([]byte, 12), 0xc0, 12)
+	if _, _, err := readDNSName(msg, 12); err == nil {
+		t.Error("expected an error for a looping pointer")
+	}
+}
+
+func TestMergeBridges(t *testing.T) {
+	mdns := []huego.Bridge{{Host: "192.168.1.20", ID: "001788fffe09a206"}}
+	ssdp := []huego.Bridge{{Host: "192.168.1.20", ID: "001788FFFE09A206"}, {Host: "192.168.1.21", ID: "001788FFFE000001"}}
+	remote := []huego.Bridge{{Host: "10.0.0.5", ID: "001788fffe000001"}, {Host: "10.0.0.6"}}
+
+	merged := Merge(mdns, ssdp, remote)
+	if len(merged) != 3 {
+		t.Fatalf("merged %d bridges, expected 3: %v", len(merged), merged)
+	}
+
+	expected := []huego.Bridge{{Host: "10.0.0.6"}, {Host: "192.168.1.21", ID: "001788FFFE000001"}, {Host: "192.168.1.20", ID: "001788FFFE09A206"}}
+	for i := range expected {
+		if merged[i].Host != expected[i].Host || merged[i].ID != expected[i].ID {
+			t.Errorf("bridge %d is %s at %s, expected %s at %s", i, merged[i].ID, merged[i].Host, expected[i].ID, expected[i].Host)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amimof/huego"
+	"github.com/spf13/viper"
+)
+
+// how often the bridge is polled while checking for or installing updates
+const firmwarePollInterval time.Duration = 5 * time.Second
+
+// the longest to wait for a check or installation to finish
+const firmwarePollTimeout time.Duration = time.Hour
+
+// device update states which mean an update is on its way, unknown and notupdatable devices have none
+var pendingUpdateStates = map[string]bool{
+	"readytoinstall": true,
+	"transferring":   true,
+	"installing":     true,
+}
+
+// firmware update state of a light or sensor, which huego does not decode
+type deviceUpdate struct {
+	Kind        string
+	ID          int
+	Name        string
+	State       string
+	LastInstall string
+}
+
+// loads the firmware update state of every light and sensor
+func loadDeviceUpdates() ([]deviceUpdate, error) {
+	var updates []deviceUpdate
+
+	for _, kind := range []string{"lights", "sensors"} {
+		result, err := bridgeRequest(myBridge, "GET", kind, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not load %s from bridge: %v", kind, err)
+		}
+
+		var devices map[string]struct {
+			Name     string `json:"name"`
+			SwUpdate *struct {
+				State       string `json:"state"`
+				LastInstall string `json:"lastinstall"`
+			} `json:"swupdate"`
+		}
+		if err := json.Unmarshal(result, &devices); err != nil {
+			return nil, fmt.Errorf("could not read %s from bridge: %v", kind, err)
+		}
+
+		for id, eachdevice := range devices {
+			// clip sensors and the daylight sensor have no firmware
+			if eachdevice.SwUpdate == nil {
+				continue
+			}
+			deviceid, _ := strconv.Atoi(id)
+			updates = append(updates, deviceUpdate{
+				Kind:        kind,
+				ID:          deviceid,
+				Name:        eachdevice.Name,
+				State:       eachdevice.SwUpdate.State,
+				LastInstall: eachdevice.SwUpdate.LastInstall,
+			})
+		}
+	}
+
+	sort.SliceStable(updates, func(i, j int) bool {
+		if updates[i].Kind == updates[j].Kind {
+			return updates[i].ID < updates[j].ID
+		}
+		return updates[i].Kind < updates[j].Kind
+	})
+
+	return updates, nil
+}
+
+// display pending bridge and device firmware updates
+func displayFirmware() {
+	myconfig, err := myBridge.GetConfig()
+	checkErr(err)
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t\n", "Setting", "Configuration")
+	fmt.Fprintf(w, "%s\t%s\t\n", "-------", "-------------")
+	fmt.Fprintf(w, "%s\t%s\t\n", "SwVersion", myconfig.SwVersion)
+	fmt.Fprintf(w, "%s\t%s\t\n", "State", myconfig.SwUpdate2.State)
+	fmt.Fprintf(w, "%s\t%s\t\n", "Bridge.State", myconfig.SwUpdate2.Bridge.State)
+	fmt.Fprintf(w, "%s\t%s\t\n", "Bridge.LastInstall", myconfig.SwUpdate2.Bridge.LastInstall)
+	fmt.Fprintf(w, "%s\t%t\t\n", "CheckForUpdate", myconfig.SwUpdate2.CheckForUpdate)
+	fmt.Fprintf(w, "%s\t%t\t\n", "AutoInstall.On", myconfig.SwUpdate2.AutoInstall.On)
+	fmt.Fprintf(w, "%s\t%s\t\n", "AutoInstall.UpdateTime", myconfig.SwUpdate2.AutoInstall.UpdateTime)
+	fmt.Fprintf(w, "%s\t%s\t\n", "LastChange", myconfig.SwUpdate2.LastChange)
+	w.Flush()
+
+	fmt.Println()
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "Type", "ID", "Name", "State", "LastInstall")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "----", "--", "----", "-----", "-----------")
+
+	updates, err := loadDeviceUpdates()
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	pending := 0
+	for _, eachupdate := range updates {
+		if pendingUpdateStates[eachupdate.State] {
+			pending++
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t\n", eachupdate.Kind, eachupdate.ID, eachupdate.Name, eachupdate.State, eachupdate.LastInstall)
+	}
+	w.Flush()
+
+	fmt.Printf("\nNumber of devices with an update pending: %d\n", pending)
+}
+
+// asks the bridge to check for updates and waits for the check to finish
+func checkFirmware() {
+	if _, err := bridgeRequest(myBridge, "PUT", "config", map[string]interface{}{"swupdate2": map[string]interface{}{"checkforupdate": true}}); err != nil {
+		fmt.Printf("ERROR: Could not start update check: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Checking for updates")
+	pollFirmware(func(config *huego.Config) bool {
+		return !config.SwUpdate2.CheckForUpdate
+	})
+
+	displayFirmware()
+}
+
+// starts installing updates which are ready and waits for the installation to finish
+func installFirmware() {
+	myconfig, err := myBridge.GetConfig()
+	checkErr(err)
+
+	if myconfig.SwUpdate2.State != "anyreadytoinstall" && myconfig.SwUpdate2.State != "allreadytoinstall" {
+		fmt.Printf("No updates are ready to install, update state is \"%s\"\n", myconfig.SwUpdate2.State)
+		return
+	}
+
+	fmt.Print("Lights may turn off or flash while updates install, start installing updates? [y/n]: ")
+	if !yesNoPrompt() {
+		fmt.Println("WARN: Aborting update install")
+		return
+	}
+
+	if _, err := bridgeRequest(myBridge, "PUT", "config", map[string]interface{}{"swupdate2": map[string]interface{}{"install": true}}); err != nil {
+		fmt.Printf("ERROR: Could not start installing updates: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Installing updates")
+	pollFirmware(func(config *huego.Config) bool {
+		return config.SwUpdate2.State != "installing" && config.SwUpdate2.State != "transferring" && !config.SwUpdate2.Install
+	})
+
+	displayFirmware()
+}
+
+// polls the bridge, printing progress whenever the update state changes, until done returns true
+func pollFirmware(done func(config *huego.Config) bool) {
+	started := time.Now()
+	laststate := ""
+
+	for time.Since(started) < firmwarePollTimeout {
+		time.Sleep(firmwarePollInterval)
+
+		// the bridge restarts while updating itself so errors are expected for a while
+		myconfig, err := myBridge.GetConfig()
+		var updates []deviceUpdate
+		if err == nil {
+			updates, err = loadDeviceUpdates()
+		}
+		if err != nil {
+			if laststate != "unreachable" {
+				fmt.Printf("%s bridge is unreachable, waiting\n", time.Now().Format("15:04:05"))
+				laststate = "unreachable"
+			}
+			continue
+		}
+
+		installing := 0
+		for _, eachupdate := range updates {
+			if eachupdate.State == "installing" || eachupdate.State == "transferring" {
+				installing++
+			}
+		}
+
+		state := fmt.Sprintf("state %s, bridge %s, %d devices updating", myconfig.SwUpdate2.State, myconfig.SwUpdate2.Bridge.State, installing)
+		if state != laststate {
+			fmt.Printf("%s %s\n", time.Now().Format("15:04:05"), state)
+			laststate = state
+		}
+
+		if done(myconfig) {
+			fmt.Printf("Finished after %s\n\n", time.Since(started).Round(time.Second))
+			return
+		}
+	}
+
+	fmt.Printf("ERROR: Gave up waiting after %s\n", firmwarePollTimeout)
+	os.Exit(1)
+}
+
+// configures whether updates install automatically and at what time
+func configureAutoInstall() {
+	autoinstall := map[string]interface{}{}
+
+	if viper.IsSet("autoinstall") {
+		autoinstall["on"] = viper.GetBool("autoinstall")
+	}
+
+	if viper.IsSet("updatetime") {
+		clock, err := parseClock(viper.GetString("updatetime"))
+		if err != nil {
+			fmt.Printf("ERROR: --updatetime %v\n", err)
+			os.Exit(1)
+		}
+		autoinstall["updatetime"] = "T" + clock
+	}
+
+	if _, err := bridgeRequest(myBridge, "PUT", "config", map[string]interface{}{"swupdate2": map[string]interface{}{"autoinstall": autoinstall}}); err != nil {
+		fmt.Printf("ERROR: Could not configure automatic updates: %v\n", err)
+		os.Exit(1)
+	}
+
+	myconfig, err := myBridge.GetConfig()
+	checkErr(err)
+	fmt.Printf("Automatic updates: %t, installing at %s\n", myconfig.SwUpdate2.AutoInstall.On, myconfig.SwUpdate2.AutoInstall.UpdateTime)
+}
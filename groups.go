@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/amimof/huego"
+)
+
+var loadedGroups []huego.Group
+
+// loads groups from the bridge preventing multiple uneccessary calls to bridge
+func loadGroups() {
+	groups, err := myBridge.GetGroups()
+	if err != nil {
+		fmt.Println("ERROR: Could not load groups from bridge")
+		os.Exit(1)
+	}
+
+	// sorting groups by ID
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+
+	loadedGroups = groups
+}
+
+// find a groupID when given the name or ID of a group
+func getGroupIDFromName(groupName string) (int, bool) {
+	if groupid, err := strconv.Atoi(groupName); err == nil {
+		for _, eachgroup := range loadedGroups {
+			if eachgroup.ID == groupid {
+				return groupid, true
+			}
+		}
+		return 0, false
+	}
+
+	for _, eachgroup := range loadedGroups {
+		if strings.EqualFold(eachgroup.Name, groupName) {
+			return eachgroup.ID, true
+		}
+	}
+	return 0, false
+}
+
+// find the name of a group when given its ID, returns an empty string if not found
+func getGroupName(groupID int) string {
+	for _, eachgroup := range loadedGroups {
+		if eachgroup.ID == groupID {
+			return eachgroup.Name
+		}
+	}
+	return ""
+}
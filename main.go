@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -12,8 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
-
-	"gopkg.in/yaml.v3"
+	"time"
 
 	"github.com/amimof/huego"
 	"github.com/spf13/pflag"
@@ -37,13 +35,17 @@ var (
 	}
 )
 
+// config file layout, Settings keeps any other options such as batterythreshold when the file is rewritten
 type huelightConfig struct {
-	Bridge      string `yaml:"bridge"`
-	Username    string `yaml:"username"`
-	Application string `yaml:"application"`
+	bridgeProfile `yaml:",inline"`
+	Default       string                   `yaml:"default,omitempty"`
+	Bridges       map[string]bridgeProfile `yaml:"bridges,omitempty"`
+	Settings      map[string]interface{}   `yaml:",inline"`
 }
 
-func init() {
+// parses the arguments and reads the config file, called from main rather than init so the tests of this package
+// need no config file
+func loadConfig() {
 	// tidy
 	flag.String("config", "config.yaml", "Configuration file: /path/to/file.yaml, default = ./config.yaml")
 	flag.Bool("displayconfig", false, "Display configuration")
@@ -55,16 +57,93 @@ func init() {
 	flag.Bool("list", false, "List lights")
 	flag.Bool("showbridge", false, "Show bridge details")
 	flag.Bool("showusers", false, "Show whitelist/all users")
+	flag.Bool("auditusers", false, "Find stale, duplicate and old whitelist users")
+	flag.Int("staledays", 90, "Days unused after which --auditusers flags a user")
 	flag.Bool("bridgeconfig", false, "Show bridge configuration")
 	flag.String("createuser", "", "Creates a user")
+	flag.Duration("pairtimeout", 30*time.Second, "How long to wait for the bridge link button when creating a user")
+	flag.Bool("generateclientkey", false, "Also generate an entertainment client key when creating a user")
+	flag.String("rotateuser", "", "Replace the configured username with a newly created one")
 	flag.String("deleteuser", "", "Deletes a user")
 	flag.Bool("findbridges", false, "Searches network for Hue Bridges")
+	flag.Duration("discoverytimeout", 3*time.Second, "How long to wait for bridges to answer mDNS and SSDP discovery")
 	flag.String("bridge", "", "Which bridge to use (IP Address)")
 	flag.String("username", "", "Username to login to bridge")
+	flag.Bool("show-secrets", false, "Show usernames and client keys instead of masking them")
 	flag.Bool("makeconfig", false, "Make a configuration file")
+	flag.Bool("yes", false, "Answer yes to every confirmation and never prompt")
+	flag.Bool("non-interactive", false, "Never prompt, taking every answer from flags or environment variables")
+	flag.String("profile", "", "Bridge profile from the configuration file to use")
+	flag.Bool("all-bridges", false, "Run list, status, backup and light actions on every bridge profile")
+	flag.Bool("listscenes", false, "List scenes")
+	flag.String("exportscenes", "", "Export a scene (name or ID) or \"all\" scenes as YAML")
+	flag.String("importscenes", "", "Import scenes from a YAML file")
+	flag.String("output", "", "File to write exported data to, default = stdout")
+	flag.Bool("auditscenes", false, "Find orphaned and duplicate scenes")
+	flag.Bool("prune", false, "Delete the scenes found by --auditscenes")
+	flag.String("group", "", "Group ID or name")
+	flag.String("scene", "", "Scene ID or name")
+	flag.Bool("listschedules", false, "List schedules")
+	flag.String("createschedule", "", "Create a schedule with this name")
+	flag.String("time", "", "When a schedule runs: \"in 30m\", \"every weekday at 07:00\", \"2026-12-24T18:00\"")
+	flag.String("enableschedule", "", "Enable a schedule (name or ID)")
+	flag.String("disableschedule", "", "Disable a schedule (name or ID)")
+	flag.String("deleteschedule", "", "Delete a schedule (name or ID)")
+	flag.Bool("listrules", false, "List rules")
+	flag.String("createrule", "", "Create a rule with this name")
+	flag.String("rule", "", "Rule to create: when <conditions> then <actions>")
+	flag.String("rulefile", "", "Create all rules in a YAML file")
+	flag.String("deleterule", "", "Delete a rule (name or ID)")
+	flag.Bool("listsensors", false, "List sensors")
+	flag.Bool("lowbattery", false, "List sensors with a low battery")
+	flag.Int("batterythreshold", 20, "Battery percentage at or below which a battery is low")
+	flag.String("motionsensor", "", "View or change a motion sensor (name or ID)")
+	flag.Int("sensitivity", 0, "Motion sensor sensitivity")
+	flag.Bool("ledindication", false, "Motion sensor LED indication")
+	flag.Bool("sensoron", true, "Enable or disable a sensor")
+	flag.Float64("darklux", 0, "Light level in lux below which it is dark")
+	flag.Float64("daylightlux", 0, "Light level in lux above which it is daylight")
+	flag.String("applydimmer", "", "Compile a dimmer switch mapping YAML file in to bridge rules")
+	flag.String("removedimmer", "", "Remove the rules of a dimmer switch mapping (name)")
+	flag.String("createmotion", "", "Create or update a motion automation with this name")
+	flag.String("sensor", "", "Sensor ID or name")
+	flag.Duration("timeout", 5*time.Minute, "How long without motion before the lights dim")
+	flag.String("brightness", "100%", "Brightness, 1-254 or a percentage")
+	flag.Bool("listmotion", false, "List motion automations")
+	flag.String("removemotion", "", "Remove a motion automation (name)")
+	flag.Bool("listflags", false, "List CLIP generic flag and status sensors")
+	flag.String("createflag", "", "Create a CLIP generic flag sensor with this name")
+	flag.String("createstatus", "", "Create a CLIP generic status sensor with this name")
+	flag.String("setsensor", "", "Set the value of a flag or status sensor (name or ID)")
+	flag.String("value", "", "Value to set")
+	flag.String("deletesensor", "", "Delete a flag or status sensor (name or ID)")
+	flag.Bool("listlinks", false, "List resourcelinks")
+	flag.String("createlink", "", "Create a resourcelink with this name")
+	flag.String("links", "", "Resources to link: type:name,... where type is light, group, scene, schedule, rule or sensor")
+	flag.String("description", "", "Description of a resourcelink")
+	flag.String("deletelink", "", "Delete a resourcelink (name or ID)")
+	flag.Bool("recursive", false, "Also delete the rules, schedules, scenes and CLIP sensors a resourcelink links")
+	flag.String("setname", "", "Change the bridge name")
+	flag.String("settimezone", "", "Change the bridge timezone")
+	flag.Int("setzigbeechannel", 0, "Change the bridge Zigbee channel: 11, 15, 20 or 25")
+	flag.Bool("dhcp", true, "Bridge uses DHCP")
+	flag.String("ipaddress", "", "Bridge static IP address")
+	flag.String("netmask", "", "Bridge static netmask")
+	flag.String("gateway", "", "Bridge static gateway")
+	flag.Bool("firmware", false, "Show pending bridge and device firmware updates")
+	flag.Bool("checkupdates", false, "Check for firmware updates")
+	flag.Bool("installupdates", false, "Install firmware updates which are ready")
+	flag.Bool("autoinstall", false, "Install firmware updates automatically")
+	flag.String("updatetime", "", "Time automatic firmware updates install, HH:MM")
+	flag.String("backup", "", "Back up the bridge to a .json or .yaml file")
+	flag.Bool("includesecrets", false, "Keep whitelist usernames in backups")
+	flag.String("restore", "", "Restore a backup file on to the bridge")
+	flag.String("diff", "", "Compare a backup file with the bridge or with --diffwith")
+	flag.String("diffwith", "", "Backup file to compare --diff with instead of the bridge")
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	pflag.Parse()
 	checkErr(viper.BindPFlags(pflag.CommandLine))
+	checkErr(bindPromptEnv())
 
 	if viper.GetBool("help") {
 		displayHelp()
@@ -92,14 +171,16 @@ func init() {
 	viper.SetConfigName(config)
 
 	if viper.GetBool("makeconfig") {
-		fmt.Print("Do you want to create a config file? [y/n]: ")
-		if yesNoPrompt() {
-			setupConfig()
-			os.Exit(0)
-		} else {
-			fmt.Println("did not want to setup a config file, exiting")
-			os.Exit(2)
+		// --makeconfig is answer enough when not prompting
+		if !nonInteractive() {
+			fmt.Print("Do you want to create a config file? [y/n]: ")
+			if !yesNoPrompt() {
+				fmt.Println("did not want to setup a config file, exiting")
+				os.Exit(2)
+			}
 		}
+		setupConfig()
+		os.Exit(0)
 	}
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -111,11 +192,21 @@ func init() {
 		}
 	}
 
+	if err := selectProfile(); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
 	if viper.GetBool("displayconfig") {
 		displayConfig()
 		os.Exit(0)
 	}
 
+	if err := loadUsername(); err != nil {
+		fmt.Printf("ERROR: Could not read username: %v\n", err)
+		os.Exit(1)
+	}
+
 	if !viper.IsSet("bridge") {
 
 		fmt.Println("no bridge set")
@@ -124,12 +215,20 @@ func init() {
 }
 
 func main() {
+	loadConfig()
+
 	if viper.IsSet("findbridges") {
 		discoverBridges()
 		printDiscoveredBridges()
 		os.Exit(0)
 	}
 
+	// comparing two backups does not need the bridge
+	if viper.IsSet("diff") && viper.IsSet("diffwith") {
+		diffBackups(viper.GetString("diff"), viper.GetString("diffwith"))
+		os.Exit(0)
+	}
+
 	user := viper.GetString("username")
 
 	if viper.IsSet("action") {
@@ -146,15 +245,13 @@ func main() {
 		}
 	}
 
-	var bridgeerr error
-	myBridge, bridgeerr = huego.Discover()
-	if bridgeerr != nil {
-		// tidy
-		panic(bridgeerr)
+	if viper.GetBool("all-bridges") {
+		allBridges()
+		os.Exit(0)
 	}
 
-	// store selected bridge ID because struct loses it once logged in
-	myBridgeID = myBridge.ID
+	// connect to the bridge from the config file or selected profile, following it if its address changed
+	connectBridge()
 
 	bridgeLogin(user)
 
@@ -163,9 +260,7 @@ func main() {
 			fmt.Printf("\nWARN: Bridge has not been set, here is a list of discovered Hue bridges:\n\n")
 			discoverBridges()
 			printDiscoveredBridges()
-			var userprompt string
-			fmt.Printf("\nPlease type the IP address of the Hue bridge you wish to use: ")
-			fmt.Scanln(&userprompt)
+			userprompt := valuePrompt("A bridge", "\nPlease type the IP address of the Hue bridge you wish to use: ", "bridge", bridgeEnvVar)
 
 			if checkBridgeValid(userprompt) {
 				fmt.Printf("Bridge found, using: %s\n", userprompt)
@@ -178,13 +273,17 @@ func main() {
 			}
 
 		}
-		didmakeuser, username := createUser(viper.GetString("createuser"))
+		didmakeuser, paired := createUser(viper.GetString("createuser"))
 		if didmakeuser {
-			fmt.Printf("Created User: %s\n", viper.GetString("createuser"))
-			fmt.Printf("    Username: %s\n\n", username)
+			fmt.Printf("Created User: %s\n", paired.DeviceType)
+			fmt.Printf("    Username: %s\n", maskSecret(paired.Username))
+			if paired.ClientKey != "" {
+				fmt.Printf("   ClientKey: %s\n", maskSecret(paired.ClientKey))
+			}
+			fmt.Println()
 			fmt.Println("Hue uses the terms \"user\" and \"username\" in a confusing way.  User typically refer to an \"application\", whereas Username refers to Hue generated secret string used like a password or an API key.  This tool uses the Username when interacting with the Hue Bridge.")
 			fmt.Println("\nCurrent whitelist/users are:")
-			bridgeLogin(username)
+			bridgeLogin(paired.Username)
 			displayUsers(myBridge)
 			os.Exit(0)
 		} else {
@@ -193,11 +292,21 @@ func main() {
 		}
 	}
 
+	if viper.IsSet("rotateuser") {
+		rotateUser(viper.GetString("rotateuser"))
+		os.Exit(0)
+	}
+
 	if viper.GetBool("showbridge") {
 		displayBridge(myBridge)
 		os.Exit(0)
 	}
 
+	if viper.GetBool("auditusers") {
+		displayUserAudit(viper.GetInt("staledays"))
+		os.Exit(0)
+	}
+
 	if viper.GetBool("showusers") {
 		displayUsers(myBridge)
 		os.Exit(0)
@@ -208,6 +317,41 @@ func main() {
 		os.Exit(0)
 	}
 
+	if viper.IsSet("setname") || viper.IsSet("settimezone") || viper.IsSet("setzigbeechannel") || viper.IsSet("dhcp") || viper.IsSet("ipaddress") || viper.IsSet("netmask") || viper.IsSet("gateway") {
+		updateBridgeSettings()
+		os.Exit(0)
+	}
+
+	if viper.IsSet("backup") {
+		backupBridge(viper.GetString("backup"))
+		os.Exit(0)
+	}
+
+	if viper.IsSet("diff") {
+		diffBackups(viper.GetString("diff"), "")
+		os.Exit(0)
+	}
+
+	if viper.GetBool("firmware") {
+		displayFirmware()
+		os.Exit(0)
+	}
+
+	if viper.GetBool("checkupdates") {
+		checkFirmware()
+		os.Exit(0)
+	}
+
+	if viper.GetBool("installupdates") {
+		installFirmware()
+		os.Exit(0)
+	}
+
+	if viper.IsSet("autoinstall") || viper.IsSet("updatetime") {
+		configureAutoInstall()
+		os.Exit(0)
+	}
+
 	// load up all the lights from bridge
 	loadLights()
 
@@ -221,6 +365,31 @@ func main() {
 		os.Exit(0)
 	}
 
+	if viper.IsSet("restore") {
+		restoreBridge(viper.GetString("restore"))
+		os.Exit(0)
+	}
+
+	if viper.GetBool("listscenes") {
+		listScenes()
+		os.Exit(0)
+	}
+
+	if viper.IsSet("exportscenes") {
+		exportScenes(viper.GetString("exportscenes"), viper.GetString("output"))
+		os.Exit(0)
+	}
+
+	if viper.IsSet("importscenes") {
+		importScenes(viper.GetString("importscenes"))
+		os.Exit(0)
+	}
+
+	if viper.GetBool("auditscenes") {
+		displaySceneAudit(viper.GetBool("prune"))
+		os.Exit(0)
+	}
+
 	if viper.IsSet("light") {
 		var lighterr error
 		lightID, lighterr = strconv.Atoi(viper.GetString("light"))
@@ -239,6 +408,139 @@ func main() {
 		fmt.Println("no light set")
 	} */
 
+	if viper.GetBool("listschedules") {
+		listSchedules()
+		os.Exit(0)
+	}
+
+	if viper.IsSet("createschedule") {
+		createSchedule(viper.GetString("createschedule"), viper.GetString("time"))
+		os.Exit(0)
+	}
+
+	if viper.IsSet("enableschedule") {
+		setScheduleStatus(viper.GetString("enableschedule"), true)
+		os.Exit(0)
+	}
+
+	if viper.IsSet("disableschedule") {
+		setScheduleStatus(viper.GetString("disableschedule"), false)
+		os.Exit(0)
+	}
+
+	if viper.IsSet("deleteschedule") {
+		deleteSchedule(viper.GetString("deleteschedule"))
+		os.Exit(0)
+	}
+
+	if viper.GetBool("listrules") {
+		listRules()
+		os.Exit(0)
+	}
+
+	if viper.IsSet("createrule") || viper.IsSet("rulefile") {
+		if viper.IsSet("rulefile") {
+			createRulesFromFile(viper.GetString("rulefile"))
+		} else {
+			createRule(viper.GetString("createrule"), viper.GetString("rule"))
+		}
+		os.Exit(0)
+	}
+
+	if viper.IsSet("deleterule") {
+		deleteRule(viper.GetString("deleterule"))
+		os.Exit(0)
+	}
+
+	if viper.GetBool("listsensors") {
+		listSensors()
+		os.Exit(0)
+	}
+
+	if viper.GetBool("lowbattery") {
+		if displayLowBattery(viper.GetInt("batterythreshold")) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if viper.IsSet("motionsensor") {
+		motionSensor(viper.GetString("motionsensor"))
+		os.Exit(0)
+	}
+
+	if viper.IsSet("applydimmer") {
+		applyDimmerMapping(viper.GetString("applydimmer"))
+		os.Exit(0)
+	}
+
+	if viper.IsSet("removedimmer") {
+		removeDimmerMapping(viper.GetString("removedimmer"))
+		os.Exit(0)
+	}
+
+	if viper.IsSet("createmotion") {
+		createMotionAutomation(motionAutomation{
+			Name:       viper.GetString("createmotion"),
+			Sensor:     viper.GetString("sensor"),
+			Group:      viper.GetString("group"),
+			Scene:      viper.GetString("scene"),
+			Brightness: viper.GetString("brightness"),
+			Timeout:    viper.GetDuration("timeout"),
+		})
+		os.Exit(0)
+	}
+
+	if viper.GetBool("listmotion") {
+		listAutomations(motionKind)
+		os.Exit(0)
+	}
+
+	if viper.IsSet("removemotion") {
+		removeMotionAutomation(viper.GetString("removemotion"))
+		os.Exit(0)
+	}
+
+	if viper.GetBool("listflags") {
+		listCLIPSensors()
+		os.Exit(0)
+	}
+
+	if viper.IsSet("createflag") {
+		createCLIPSensorCommand(viper.GetString("createflag"), "CLIPGenericFlag")
+		os.Exit(0)
+	}
+
+	if viper.IsSet("createstatus") {
+		createCLIPSensorCommand(viper.GetString("createstatus"), "CLIPGenericStatus")
+		os.Exit(0)
+	}
+
+	if viper.IsSet("setsensor") {
+		setCLIPSensor(viper.GetString("setsensor"), viper.GetString("value"))
+		os.Exit(0)
+	}
+
+	if viper.IsSet("deletesensor") {
+		deleteCLIPSensor(viper.GetString("deletesensor"))
+		os.Exit(0)
+	}
+
+	if viper.GetBool("listlinks") {
+		listResourcelinks()
+		os.Exit(0)
+	}
+
+	if viper.IsSet("createlink") {
+		createResourcelink(viper.GetString("createlink"), viper.GetString("description"), viper.GetString("links"))
+		os.Exit(0)
+	}
+
+	if viper.IsSet("deletelink") {
+		deleteResourcelink(viper.GetString("deletelink"), viper.GetBool("recursive"))
+		os.Exit(0)
+	}
+
 	if viper.IsSet("list") || viper.IsSet("listall") {
 		listLights()
 	}
@@ -256,7 +558,7 @@ func main() {
 
 // displays configuration
 func displayConfig() {
-	allmysettings := viper.AllSettings()
+	allmysettings := maskSettings(viper.AllSettings())
 	var keys []string
 	for k := range allmysettings {
 		keys = append(keys, k)
@@ -279,15 +581,106 @@ func displayHelp() {
       --listall                 List all details about the lights
       --action                  Do actions
       --showusers               List all user/whitelist details
+      --auditusers              Flag users unused for --staledays or with duplicate devicetypes, marking old huelights credentials,
+                                with a checklist for removing them at https://account.meethue.com/apps
+      --staledays [days]        Days unused after which --auditusers flags a user (default 90)
       --showbridge              Show logged in bridge details
       --light                   Select a light
       --bridgeconfig            Show bridge configuration
-      --createuser [username]   Creates a user
+      --createuser [label]      Creates a user named huelights#[label], or huelights#[hostname] when the label is empty,
+                                waiting for the bridge link button to be pressed, and saves it to the config
+      --pairtimeout [time]      How long to wait for the link button, like 60s (default 30s)
+      --generateclientkey       Also generate an entertainment client key when creating a user, saved with the username,
+                                so a username read from usernameenv or usernamecommand needs usernamefile as well
+      --rotateuser [label]      Create a new user like --createuser, verify it and replace the username in the config,
+                                then list the previous username as stale
       --deleteuser              Deletes a user
-      --findbridges             Discover Hue bridges on network
+      --findbridges             Discover Hue bridges on network by mDNS, SSDP and the Hue discovery service
+      --discoverytimeout [time] How long to wait for bridges to answer mDNS and SSDP, like 5s (default 3s)
       --bridge                  Which bridge to use (IP Address)
-      --username                Username to login to bridge
-      --makeconfig              Make a configuration file
+      --username                Username to login to bridge, or set usernameenv, usernamefile or usernamecommand in
+                                the config to read it from an environment variable, a 0600 file or a command's output,
+                                otherwise it is read from HUELIGHTS_USERNAME when the config has none
+      --show-secrets            Show usernames and client keys instead of masking them
+      --makeconfig              Make a configuration file, or add a bridge profile to an existing one
+      --yes                     Answer yes to every confirmation and never prompt, also HUELIGHTS_YES=true
+      --non-interactive         Never prompt, taking answers from flags or HUELIGHTS_CONFIG, HUELIGHTS_PROFILE,
+                                HUELIGHTS_BRIDGE and HUELIGHTS_USERNAME, and failing when an answer or a --yes
+                                confirmation is missing, also HUELIGHTS_NON_INTERACTIVE=true
+      --profile [name]          Bridge profile from the configuration file to use, default is the "default" setting
+      --all-bridges             Run --list, --listall, --backup or --light with --action on every bridge profile at once,
+                                results are prefixed with the profile name and backups get it added to their file name
+      --listscenes              List scenes
+      --exportscenes [scene]    Export a scene (name or ID) or "all" scenes as YAML
+      --importscenes [file]     Import scenes from a YAML file
+      --output [file]           File to write exported data to (default stdout)
+      --auditscenes             Find orphaned and duplicate scenes
+      --prune                   Delete the scenes found by --auditscenes
+      --group                   Select a group
+      --scene                   Select a scene
+      --listschedules           List schedules
+      --createschedule [name]   Create a schedule running --action on --light or --group, or recalling --scene
+      --time [when]             When a schedule runs: "in 30m", "every 10m", "every weekday at 07:00",
+                                "every mon,fri at 18:30" or "2026-12-24T18:00"
+      --enableschedule [name]   Enable a schedule
+      --disableschedule [name]  Disable a schedule
+      --deleteschedule [name]   Delete a schedule
+      --listrules               List rules
+      --createrule [name]       Create a rule from --rule
+      --rule [rule]             Rule to create, for example:
+                                'when "Hall motion".presence == true and daylight == false then group Hall on bri 50%'
+      --rulefile [file]         Create all rules in a YAML file
+      --deleterule [name]       Delete a rule
+      --listsensors             List sensors with readable values and battery level
+      --lowbattery              List sensors with a low battery, exits with 1 when any are found
+      --batterythreshold [%]    Battery percentage at or below which a battery is low (default 20)
+      --motionsensor [name]     View a motion sensor, or change it with the following settings
+      --sensitivity [n]         Motion sensitivity, from 0 to the sensors maximum
+      --ledindication=[bool]    Turn the LED indication on or off
+      --sensoron=[bool]         Enable or disable the sensor
+      --darklux [lux]           Light level below which it is dark
+      --daylightlux [lux]       Light level above which it is daylight
+      --applydimmer [file]      Compile a dimmer switch mapping YAML file in to bridge rules, replacing any previous apply
+      --removedimmer [name]     Remove the rules and sensors of a dimmer switch mapping
+      --createmotion [name]     Create a motion automation turning on --group when --sensor sees motion and it is dark,
+                                dimming after --timeout without motion and turning off a minute later.
+                                Running it again with the same name updates the automation
+      --sensor                  Select a sensor
+      --timeout [duration]      How long without motion before the lights dim (default 5m)
+      --brightness [bri]        Brightness the lights turn on at, 1-254 or a percentage, unless --scene is given (default 100%)
+      --listmotion              List motion automations and their rules
+      --removemotion [name]     Remove a motion automation along with its rules and sensors
+      --listflags               List CLIP generic flag and status sensors
+      --createflag [name]       Create a CLIP generic flag sensor
+      --createstatus [name]     Create a CLIP generic status sensor
+      --setsensor [name]        Set a flag sensor to --value true/false, or a status sensor to a --value number
+      --value [value]           Value to set
+      --deletesensor [name]     Delete a flag or status sensor
+      --listlinks               List resourcelinks and the resources they link
+      --createlink [name]       Create a resourcelink linking --links, with an optional --description
+      --links [links]           Resources to link, for example: "rule:Hall on,sensor:Away mode,schedule:Wake up"
+      --description [text]      Description of a resourcelink
+      --deletelink [name]       Delete a resourcelink
+      --recursive               With --deletelink, also delete the linked rules, schedules, scenes and CLIP sensors
+      --setname [name]          Change the bridge name
+      --settimezone [timezone]  Change the bridge timezone, for example Europe/London
+      --setzigbeechannel [n]    Change the bridge Zigbee channel: 11, 15, 20 or 25
+      --dhcp=[bool]             Change whether the bridge uses DHCP
+      --ipaddress [ip]          Change the bridge static IP address, needs --dhcp=false
+      --netmask [ip]            Change the bridge static netmask
+      --gateway [ip]            Change the bridge static gateway
+      --firmware                Show pending bridge and device firmware updates
+      --checkupdates            Check for firmware updates and wait for the check to finish
+      --installupdates          Install firmware updates which are ready and wait for them to finish
+      --autoinstall=[bool]      Change whether firmware updates install automatically
+      --updatetime [HH:MM]      Change the time automatic firmware updates install
+      --backup [file]           Back up lights, groups, scenes, schedules, rules, sensors, resourcelinks and config
+                                to a .json or .yaml file, whitelist usernames are redacted
+      --includesecrets          Keep whitelist usernames in backups
+      --restore [file]          Restore groups, scenes, schedules, rules, CLIP sensors and resourcelinks from a backup,
+                                lights and sensors are matched by uniqueid and unmapped items are reported
+      --diff [file]             Show resources added, removed and changed since a backup was taken, exits 1 if any differ
+      --diffwith [file]         Compare --diff with this backup instead of the bridge
 `
 	fmt.Println(applicationName + " " + applicationVersion)
 	fmt.Println(message)
@@ -365,7 +758,7 @@ func displayBridge(thisBridge *huego.Bridge) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
 	fmt.Fprintf(w, "%-15s\t%s\t%s\t\n", "Host", "BridgeID", "User")
 	fmt.Fprintf(w, "%-15s\t%s\t%s\t\n", "---------------", "--------", "----")
-	fmt.Fprintf(w, "%-15s\t%s\t%s\t\n", thisBridge.Host, myBridgeID, thisBridge.User)
+	fmt.Fprintf(w, "%-15s\t%s\t%s\t\n", thisBridge.Host, myBridgeID, maskSecret(thisBridge.User))
 	w.Flush()
 }
 
@@ -388,7 +781,7 @@ func displayUsers(thisBridge *huego.Bridge) {
 	})
 
 	for _, eachuser := range allusers {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", eachuser.Name, eachuser.Username, eachuser.CreateDate, eachuser.LastUseDate, eachuser.ClientKey)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", eachuser.Name, maskSecret(eachuser.Username), eachuser.CreateDate, eachuser.LastUseDate, maskSecret(eachuser.ClientKey))
 	}
 
 	w.Flush()
@@ -476,10 +869,10 @@ func displayBridgeConfig() {
 
 	for i, key := range myconfig.Whitelist {
 		fmt.Fprintf(w, "%s%d%s\t%s\t\n", "Whitelist.", i, ".Name", key.Name)
-		fmt.Fprintf(w, "%s%d%s\t%s\t\n", "Whitelist.", i, ".Username", key.Username)
+		fmt.Fprintf(w, "%s%d%s\t%s\t\n", "Whitelist.", i, ".Username", maskSecret(key.Username))
 		fmt.Fprintf(w, "%s%d%s\t%s\t\n", "Whitelist.", i, ".CreateDate", key.CreateDate)
 		fmt.Fprintf(w, "%s%d%s\t%s\t\n", "Whitelist.", i, ".LastUseDate", key.LastUseDate)
-		fmt.Fprintf(w, "%s%d%s\t%s\t\n", "Whitelist.", i, ".ClientKey", key.ClientKey)
+		fmt.Fprintf(w, "%s%d%s\t%s\t\n", "Whitelist.", i, ".ClientKey", maskSecret(key.ClientKey))
 	}
 
 	fmt.Fprintf(w, "%s\t%t\t\n", "PortalState.SignedOn", myconfig.PortalState.SignedOn)
@@ -559,56 +952,75 @@ func getLightIDFromName(lightName string) (int, bool) {
 	return 0, false
 }
 
+// find the name of a light when given its ID, returns an empty string if not found
+func getLightName(findLightID int) string {
+	for _, eachlight := range loadedLights {
+		if eachlight.ID == findLightID {
+			return eachlight.Name
+		}
+	}
+	return ""
+}
+
+// find a light when given its uniqueid
+func getLightFromUniqueID(uniqueID string) (huego.Light, bool) {
+	for _, eachlight := range loadedLights {
+		if uniqueID != "" && strings.EqualFold(eachlight.UniqueID, uniqueID) {
+			return eachlight, true
+		}
+	}
+	return huego.Light{}, false
+}
+
 // have lights been loaded?
 func areLightsLoaded() bool {
 	return len(loadedLights) > 0
 }
 
-// check if a user exists
-func doesUserExist(checkuser string) bool {
-	fmt.Println("starting doesuserexist")
+// check if a user exists, matching whitelist names against the application#device convention
+func doesUserExist(devicetype string) bool {
 	allusers, err := myBridge.GetUsers()
-	checkErr(err)
-
-	prettyPrint(allusers)
+	if err != nil {
+		// users can only be listed with a valid username, so a first pairing cannot check
+		return false
+	}
 
 	for _, eachuser := range allusers {
-		if strings.EqualFold(strings.ToLower(eachuser.Name), checkuser) {
-			fmt.Printf("Found user: %s\n", checkuser)
+		if strings.EqualFold(eachuser.Name, devicetype) {
+			fmt.Printf("Found user: %s\n", eachuser.Name)
 			return true
 		}
 	}
 
-	fmt.Printf("NOT found user: %s\n", checkuser)
 	return false
 }
 
-// creates a user/app/whitelist
-func createUser(newuser string) (bool, string) {
+// creates a user/app/whitelist by polling the bridge until its link button is pressed, then saves it to the config file
+func createUser(label string) (bool, *pairedUser) {
 
-	if doesUserExist(newuser) {
-		// user already exists
-		fmt.Println("ERROR: user already exists")
-		return false, ""
+	devicetype, err := buildDeviceType(label)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return false, nil
 	}
 
-	// user doesn't exists so lets create one
-	fmt.Println("creating user here")
-	var userprompt string
-	fmt.Println("To create the user you must first press the button on Hue Bridge.  Please press the button then return here and press the [return] key")
-	fmt.Scanln(&userprompt)
-	username, err := myBridge.CreateUser(newuser)
-
-	if err == nil {
-		return true, username
+	// existing users can only be listed with a username
+	if myBridge.User != "" && doesUserExist(devicetype) {
+		// user already exists
+		fmt.Printf("ERROR: user \"%s\" already exists on this bridge, use --createuser [label] to create another\n", devicetype)
+		return false, nil
 	}
 
-	fmt.Println("==============")
+	fmt.Printf("Creating user: %s\n", devicetype)
+	paired, err := pairUser(devicetype, viper.GetDuration("pairtimeout"), viper.GetBool("generateclientkey"))
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		return false, nil
+	}
 
-	fmt.Println(prettyPrint(err))
-	fmt.Println("==============")
+	saveUsername(paired)
 
-	return false, ""
+	return true, paired
 }
 
 // pretty print a struct
@@ -622,29 +1034,6 @@ func bridgeLogin(loginas string) {
 	myBridge = myBridge.Login(loginas)
 }
 
-// discover all bridges
-func discoverBridges() {
-	var brerr error
-	foundBridges, brerr = huego.DiscoverAll()
-	checkErr(brerr)
-
-	/*
-		if len(foundBridges) < 1 {
-			fmt.Println("ERROR: No Hue bridges found on network")
-			os.Exit(1)
-		}
-		const padding = 1
-		w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
-		fmt.Fprintf(w, "%s\t%s\t\n", "IP Address", "ID")
-		fmt.Fprintf(w, "%s\t%s\t\n", "----------", "--")
-		for _, eachbridge := range foundBridges {
-			fmt.Fprintf(w, "%s\t%s\t\n", eachbridge.Host, eachbridge.ID)
-		}
-		w.Flush()
-		fmt.Printf("\nFound %d bridges\n", len(foundBridges))
-	*/
-}
-
 // checks if a bridge is valid
 func checkBridgeValid(mybridge string) bool {
 	if len(foundBridges) < 1 {
@@ -673,14 +1062,14 @@ func checkBridgeValid(mybridge string) bool {
 //	return returnbridge
 //}
 
-// sets up configuration
+// sets up configuration, adding a profile when the config file already exists
 func setupConfig() {
 
-	var myNewConfig huelightConfig
+	var myNewProfile bridgeProfile
 
 	var newConfigFile string
 
-	if !viper.IsSet("config") {
+	if !viper.IsSet("config") && !nonInteractive() {
 		var userprompt string
 		fmt.Println()
 		fmt.Printf("The default configuration file %s looks for is \"config.yaml\" in the current directory.\n\nIf you choose a different name it will need to end in .yml or .yaml and always be passed to %s with the --config [filename] argument.\n", applicationName, applicationName)
@@ -695,70 +1084,91 @@ func setupConfig() {
 
 		newConfigFile = userprompt
 	} else {
-		// fix: check config file before blatting it
 		newConfigFile = viper.GetString("config")
 	}
 
-	discoverBridges()
-
-	if !viper.IsSet("bridge") {
-
-		fmt.Println()
-		printDiscoveredBridges()
+	myNewConfig, configExists, err := readConfigFile(newConfigFile)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
 
-		var userprompt string
-		fmt.Print("\nPlease type the IP of bridge you want to use: ")
-		fmt.Scanln(&userprompt)
+	// an existing config file keeps its bridges and has this one added as a profile
+	profileName := strings.ToLower(viper.GetString("profile"))
+	if configExists && profileName == "" {
+		fmt.Printf("\nConfig file \"%s\" already exists, the new bridge will be added to it as a profile.\n", newConfigFile)
+		profileName = strings.ToLower(valuePrompt("A profile name", "Please choose a profile name: ", "profile", promptEnvVars["profile"]))
+		if profileName == "" {
+			fmt.Println("profile name is empty, exiting")
+			os.Exit(1)
+		}
+	}
 
-		// fix: improve the checking of file
+	if _, found := myNewConfig.Bridges[profileKey(myNewConfig, profileName)]; found {
+		fmt.Printf("WARN: Profile \"%s\" already exists in \"%s\", do you wish to replace it [y/n]: ", profileName, newConfigFile)
+		if !yesNoPrompt() {
+			os.Exit(1)
+		}
+	}
 
-		myNewConfig.Bridge = userprompt
+	discoverBridges()
 
-	} else {
-		myNewConfig.Bridge = viper.GetString("bridge")
+	if !viper.IsSet("bridge") && os.Getenv(bridgeEnvVar) == "" && !nonInteractive() {
+		fmt.Println()
+		printDiscoveredBridges()
 	}
 
+	// fix: improve the checking of file
+	myNewProfile.Bridge = valuePrompt("A bridge", "\nPlease type the IP of bridge you want to use: ", "bridge", bridgeEnvVar)
+
 	// check if bridge is valid
-	if !checkBridgeValid(myNewConfig.Bridge) {
-		fmt.Printf("WARN: Bridge \"%s\" is not valid, do you wish to continue [y/n]: ", myNewConfig.Bridge)
+	if !checkBridgeValid(myNewProfile.Bridge) {
+		fmt.Printf("WARN: Bridge \"%s\" is not valid, do you wish to continue [y/n]: ", myNewProfile.Bridge)
 		if !yesNoPrompt() {
 			os.Exit(1)
 		}
 	}
 
-	if !viper.IsSet("username") {
-		var userprompt string
-		fmt.Print("Please type a username: ")
-		fmt.Scanln(&userprompt)
-
+	// a username is optional, one can be created once the config is saved
+	if viper.IsSet("username") || os.Getenv(usernameEnvDefault) != "" || !nonInteractive() {
 		// fix: check username
-
-		myNewConfig.Username = userprompt
+		myNewProfile.Username = valuePrompt("A username", "Please type a username: ", "username", usernameEnvDefault)
 	} else {
-		myNewConfig.Username = viper.GetString("username")
+		fmt.Println("WARN: No username given, create one with --createuser once the config is saved")
 	}
 
-	myNewConfig.Application = applicationName
+	// the bridge ID lets the bridge be verified and found again if its address changes
+	myNewProfile.BridgeID = lookupBridgeID(myNewProfile.Bridge)
+
+	myNewProfile.Application = applicationName
+
+	if profileName == "" {
+		myNewConfig.bridgeProfile = myNewProfile
+	} else {
+		addProfile(&myNewConfig, profileName, myNewProfile)
+	}
 
 	fmt.Println("---------------")
 	fmt.Printf("Config file: %s\n", newConfigFile)
-	fmt.Printf("     Bridge: %s\n", myNewConfig.Bridge)
-	fmt.Printf("   Username: %s\n", myNewConfig.Username)
-	fmt.Printf("Application: %s\n", myNewConfig.Application)
+	if profileName != "" {
+		fmt.Printf("    Profile: %s\n", profileName)
+		fmt.Printf("    Default: %s\n", myNewConfig.Default)
+	}
+	fmt.Printf("     Bridge: %s\n", myNewProfile.Bridge)
+	fmt.Printf("   BridgeID: %s\n", myNewProfile.BridgeID)
+	fmt.Printf("   Username: %s\n", maskSecret(myNewProfile.Username))
+	fmt.Printf("Application: %s\n", myNewProfile.Application)
 	fmt.Println()
 
-	fmt.Printf("Save this configuration to file \"%s\" [y/n]: ", newConfigFile)
-	if yesNoPrompt() {
+	save := true
+	if !nonInteractive() {
+		fmt.Printf("Save this configuration to file \"%s\" [y/n]: ", newConfigFile)
+		save = yesNoPrompt()
+	}
+	if save {
 		fmt.Println("Saving configuration")
 
-		yamlData, err := yaml.Marshal(&myNewConfig)
-
-		if err != nil {
-			fmt.Printf("ERROR: Cannot generate configuration. %v", err)
-		}
-
-		err = ioutil.WriteFile(newConfigFile, yamlData, 0644)
-		if err != nil {
+		if err := writeConfigFile(newConfigFile, myNewConfig); err != nil {
 			fmt.Printf("ERROR: Unable to save into the file: %s\n", newConfigFile)
 			fmt.Println(err)
 			os.Exit(1)
@@ -786,8 +1196,18 @@ func printDiscoveredBridges() {
 	fmt.Printf("\nFound %d bridges\n", len(foundBridges))
 }
 
-// simple yes or no prompt, returns true if y or yes
+// simple yes or no prompt, returns true if y or yes, answering yes with --yes and failing when non-interactive without it
 func yesNoPrompt() bool {
+	if viper.GetBool("yes") {
+		fmt.Println("y (--yes)")
+		return true
+	}
+	if nonInteractive() {
+		fmt.Println()
+		fmt.Println("ERROR: This needs confirming, pass --yes to answer yes when running non-interactively")
+		os.Exit(1)
+	}
+
 	var userprompt string
 	fmt.Scanln(&userprompt)
 	if strings.EqualFold(userprompt, "y") || strings.EqualFold(userprompt, "yes") {
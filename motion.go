@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/amimof/huego"
+)
+
+const motionKind string = "motion"
+
+// brightness the lights dim to before turning off
+const motionDimBrightness string = "20%"
+
+// how long the lights stay dimmed before turning off
+const motionDimDuration time.Duration = time.Minute
+
+// states of the status sensor tracking a motion automation
+const (
+	motionStateIdle   int = 0
+	motionStateOn     int = 1
+	motionStateDimmed int = 2
+)
+
+// settings of a motion automation
+type motionAutomation struct {
+	Name       string
+	Sensor     string
+	Group      string
+	Scene      string
+	Brightness string
+	Timeout    time.Duration
+}
+
+// compiles a motion automation in to bridge rules, creating the status sensor tracking whether the lights were turned on by motion
+func compileMotionAutomation(settings motionAutomation) ([]int, []*huego.Rule, error) {
+	presenceid, found := getSensorIDFromName(settings.Sensor)
+	if !found {
+		return nil, nil, fmt.Errorf("\"%s\" is not a valid sensor name or sensor id", settings.Sensor)
+	}
+
+	presence, _ := getSensor(presenceid)
+	if presence.Type != "ZLLPresence" && presence.Type != "CLIPPresence" {
+		return nil, nil, fmt.Errorf("sensor \"%s\" is a %s, not a motion sensor", presence.Name, presence.Type)
+	}
+
+	groupid, found := getGroupIDFromName(settings.Group)
+	if !found {
+		return nil, nil, fmt.Errorf("\"%s\" is not a valid group name or group id", settings.Group)
+	}
+
+	if settings.Timeout < time.Minute || settings.Timeout+motionDimDuration >= 24*time.Hour {
+		return nil, nil, fmt.Errorf("timeout must be at least 1m and less than 23h")
+	}
+
+	darkness, err := motionDarkCondition(presence)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the lights are turned on with a scene, or a brightness when no scene is given
+	onBody := map[string]interface{}{"on": true}
+	if settings.Scene != "" {
+		sceneid, err := dimmerSceneID(loadScenes(), settings.Scene)
+		if err != nil {
+			return nil, nil, err
+		}
+		onBody = map[string]interface{}{"scene": sceneid}
+	} else {
+		bri, err := parseBrightness(settings.Brightness)
+		if err != nil {
+			return nil, nil, err
+		}
+		onBody["bri"] = bri
+	}
+
+	dimBri, err := parseBrightness(motionDimBrightness)
+	checkErr(err)
+
+	dimTimer, err := formatTimer(settings.Timeout.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	offTimer, err := formatTimer((settings.Timeout + motionDimDuration).String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statusid, err := createStatusSensor(automationResourceName(settings.Name, "state"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create status sensor: %v", err)
+	}
+	sensors := []int{statusid}
+
+	presenceAddress := fmt.Sprintf("/sensors/%d/state/presence", presenceid)
+	statusAddress := fmt.Sprintf("/sensors/%d/state/status", statusid)
+	groupAddress := fmt.Sprintf("/groups/%d/action", groupid)
+
+	setStatus := func(status int) *huego.RuleAction {
+		return &huego.RuleAction{Address: fmt.Sprintf("/sensors/%d/state", statusid), Method: "PUT", Body: map[string]interface{}{"status": status}}
+	}
+
+	rules := []*huego.Rule{
+		{
+			// motion while dark turns the lights on
+			Name: automationResourceName(settings.Name, "on"),
+			Conditions: []*huego.Condition{
+				{Address: presenceAddress, Operator: "eq", Value: "true"},
+				{Address: presenceAddress, Operator: "dx"},
+				darkness,
+				{Address: statusAddress, Operator: "eq", Value: strconv.Itoa(motionStateIdle)},
+			},
+			Actions: []*huego.RuleAction{
+				{Address: groupAddress, Method: "PUT", Body: onBody},
+				setStatus(motionStateOn),
+			},
+		},
+		{
+			// motion while dimmed restores the lights, the lights being on means it may no longer be dark
+			Name: automationResourceName(settings.Name, "restore"),
+			Conditions: []*huego.Condition{
+				{Address: presenceAddress, Operator: "eq", Value: "true"},
+				{Address: presenceAddress, Operator: "dx"},
+				{Address: statusAddress, Operator: "eq", Value: strconv.Itoa(motionStateDimmed)},
+			},
+			Actions: []*huego.RuleAction{
+				{Address: groupAddress, Method: "PUT", Body: onBody},
+				setStatus(motionStateOn),
+			},
+		},
+		{
+			Name: automationResourceName(settings.Name, "dim"),
+			Conditions: []*huego.Condition{
+				{Address: presenceAddress, Operator: "eq", Value: "false"},
+				{Address: presenceAddress, Operator: "ddx", Value: dimTimer},
+				{Address: statusAddress, Operator: "eq", Value: strconv.Itoa(motionStateOn)},
+			},
+			Actions: []*huego.RuleAction{
+				{Address: groupAddress, Method: "PUT", Body: map[string]interface{}{"bri": dimBri, "transitiontime": 50}},
+				setStatus(motionStateDimmed),
+			},
+		},
+		{
+			Name: automationResourceName(settings.Name, "off"),
+			Conditions: []*huego.Condition{
+				{Address: presenceAddress, Operator: "eq", Value: "false"},
+				{Address: presenceAddress, Operator: "ddx", Value: offTimer},
+				{Address: statusAddress, Operator: "eq", Value: strconv.Itoa(motionStateDimmed)},
+			},
+			Actions: []*huego.RuleAction{
+				{Address: groupAddress, Method: "PUT", Body: map[string]interface{}{"on": false}},
+				setStatus(motionStateIdle),
+			},
+		},
+	}
+
+	return sensors, rules, nil
+}
+
+// returns the condition checking it is dark, from the motion sensors own light level sensor or the bridge daylight sensor
+func motionDarkCondition(presence huego.Sensor) (*huego.Condition, error) {
+	device := sensorDeviceID(presence.UniqueID)
+	for _, eachsensor := range loadedSensors {
+		if eachsensor.Type == "ZLLLightLevel" && device != "" && sensorDeviceID(eachsensor.UniqueID) == device {
+			return &huego.Condition{Address: fmt.Sprintf("/sensors/%d/state/dark", eachsensor.ID), Operator: "eq", Value: "true"}, nil
+		}
+	}
+
+	daylight, found := getSensorByType("Daylight")
+	if !found {
+		return nil, fmt.Errorf("sensor \"%s\" has no light level sensor and the bridge has no Daylight sensor", presence.Name)
+	}
+	return &huego.Condition{Address: fmt.Sprintf("/sensors/%d/state/daylight", daylight.ID), Operator: "eq", Value: "false"}, nil
+}
+
+// creates a motion automation, replacing one of the same name
+func createMotionAutomation(settings motionAutomation) {
+	loadGroups()
+	loadSensors()
+
+	if len(settings.Name) < 1 || len(settings.Name) > bridgeNameLength {
+		fmt.Printf("ERROR: Motion automation name \"%s\" must be between 1 and %d characters\n", settings.Name, bridgeNameLength)
+		os.Exit(1)
+	}
+
+	// the existing automation is only removed once its replacement is working, so a bad update never leaves the room dark
+	existing, replacing := findAutomation(motionKind, settings.Name)
+
+	sensors, rules, err := compileMotionAutomation(settings)
+	if err != nil {
+		fmt.Printf("ERROR: Motion automation \"%s\": %v\n", settings.Name, err)
+		removeLinkedResources(sensorLinks(sensors))
+		os.Exit(1)
+	}
+
+	if err := applyAutomation(motionKind, settings.Name, sensors, rules); err != nil {
+		fmt.Printf("ERROR: Motion automation \"%s\": %v\n", settings.Name, err)
+		if replacing {
+			fmt.Println("The existing motion automation was left in place")
+		}
+		os.Exit(1)
+	}
+
+	if replacing {
+		fmt.Printf("Removing the motion automation \"%s\" it replaces\n", settings.Name)
+		removeAutomation(existing)
+	}
+}
+
+// removes a motion automation along with its rules and sensors
+func removeMotionAutomation(name string) {
+	existing, found := findAutomation(motionKind, name)
+	if !found {
+		fmt.Printf("ERROR: \"%s\" is not a motion automation created by %s\n", name, applicationName)
+		os.Exit(1)
+	}
+	removeAutomation(existing)
+}
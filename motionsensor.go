@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/amimof/huego"
+	"github.com/spf13/viper"
+)
+
+// the sensor types making up one physical hue motion sensor
+var motionSensorTypes = []string{"ZLLPresence", "ZLLLightLevel", "ZLLTemperature"}
+
+// finds the sub-sensors of the physical motion sensor that one of its sensors belongs to
+func findMotionSensor(selector string) []huego.Sensor {
+	sensorid, found := getSensorIDFromName(selector)
+	if !found {
+		fmt.Printf("ERROR: \"%s\" is not a valid sensor name or sensor id\n", selector)
+		os.Exit(1)
+	}
+
+	selected, _ := getSensor(sensorid)
+	device := sensorDeviceID(selected.UniqueID)
+	if device == "" {
+		fmt.Printf("ERROR: Sensor \"%s\" has no uniqueid so is not a motion sensor\n", selected.Name)
+		os.Exit(1)
+	}
+
+	var subsensors []huego.Sensor
+	for _, eachtype := range motionSensorTypes {
+		for _, eachsensor := range loadedSensors {
+			if eachsensor.Type == eachtype && sensorDeviceID(eachsensor.UniqueID) == device {
+				subsensors = append(subsensors, eachsensor)
+			}
+		}
+	}
+
+	if len(subsensors) < 1 || subsensors[0].Type != "ZLLPresence" {
+		fmt.Printf("ERROR: Sensor \"%s\" is not part of a motion sensor\n", selected.Name)
+		os.Exit(1)
+	}
+
+	return subsensors
+}
+
+// display the configuration of a motion sensor and its companion light level and temperature sensors
+func displayMotionSensor(subsensors []huego.Sensor) {
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t\n", "Setting", "Configuration")
+	fmt.Fprintf(w, "%s\t%s\t\n", "-------", "-------------")
+	fmt.Fprintf(w, "%s\t%s\t\n", "Device", sensorDeviceID(subsensors[0].UniqueID))
+
+	for _, eachsensor := range subsensors {
+		prefix := strings.TrimPrefix(eachsensor.Type, "ZLL")
+		fmt.Fprintf(w, "%s.ID\t%d\t\n", prefix, eachsensor.ID)
+		fmt.Fprintf(w, "%s.Name\t%s\t\n", prefix, eachsensor.Name)
+		fmt.Fprintf(w, "%s.On\t%v\t\n", prefix, eachsensor.Config["on"])
+		fmt.Fprintf(w, "%s.Reachable\t%t\t\n", prefix, sensorReachable(eachsensor))
+		fmt.Fprintf(w, "%s.Value\t%s\t\n", prefix, describeSensorState(eachsensor))
+
+		switch eachsensor.Type {
+		case "ZLLPresence":
+			fmt.Fprintf(w, "%s.Sensitivity\t%v of %v\t\n", prefix, eachsensor.Config["sensitivity"], eachsensor.Config["sensitivitymax"])
+			fmt.Fprintf(w, "%s.LedIndication\t%v\t\n", prefix, eachsensor.Config["ledindication"])
+			if battery, ok := sensorBattery(eachsensor); ok {
+				fmt.Fprintf(w, "%s.Battery\t%d%%\t\n", prefix, battery)
+			}
+		case "ZLLLightLevel":
+			tholddark, darkok := eachsensor.Config["tholddark"].(float64)
+			tholdoffset, offsetok := eachsensor.Config["tholdoffset"].(float64)
+			if darkok && offsetok {
+				fmt.Fprintf(w, "%s.DarkThreshold\t%.0f (%.0f lux)\t\n", prefix, tholddark, lightLevelToLux(tholddark))
+				fmt.Fprintf(w, "%s.DaylightThreshold\t%.0f (%.0f lux)\t\n", prefix, tholddark+tholdoffset, lightLevelToLux(tholddark+tholdoffset))
+			}
+		}
+	}
+
+	w.Flush()
+}
+
+// applies motion sensor settings passed on the command line, returns true if anything was changed
+func configureMotionSensor(subsensors []huego.Sensor) bool {
+	changed := false
+
+	for _, eachsensor := range subsensors {
+		settings := map[string]interface{}{}
+
+		// enabling or disabling applies to the whole physical device
+		if viper.IsSet("sensoron") {
+			settings["on"] = viper.GetBool("sensoron")
+		}
+
+		switch eachsensor.Type {
+		case "ZLLPresence":
+			if viper.IsSet("sensitivity") {
+				sensitivity := viper.GetInt("sensitivity")
+				if sensitivitymax, ok := eachsensor.Config["sensitivitymax"].(float64); ok && (sensitivity < 0 || sensitivity > int(sensitivitymax)) {
+					fmt.Printf("ERROR: --sensitivity must be between 0 and %.0f\n", sensitivitymax)
+					os.Exit(1)
+				}
+				settings["sensitivity"] = sensitivity
+			}
+			if viper.IsSet("ledindication") {
+				settings["ledindication"] = viper.GetBool("ledindication")
+			}
+
+		case "ZLLLightLevel":
+			if viper.IsSet("darklux") || viper.IsSet("daylightlux") {
+				tholddark, _ := eachsensor.Config["tholddark"].(float64)
+				tholdoffset, _ := eachsensor.Config["tholdoffset"].(float64)
+				dark := int(tholddark)
+				daylight := int(tholddark + tholdoffset)
+
+				if viper.IsSet("darklux") {
+					dark = luxToLightLevel(viper.GetFloat64("darklux"))
+				}
+				if viper.IsSet("daylightlux") {
+					daylight = luxToLightLevel(viper.GetFloat64("daylightlux"))
+				}
+
+				if daylight <= dark {
+					fmt.Println("ERROR: --daylightlux must be brighter than --darklux")
+					os.Exit(1)
+				}
+
+				settings["tholddark"] = dark
+				settings["tholdoffset"] = daylight - dark
+			}
+		}
+
+		if len(settings) < 1 {
+			continue
+		}
+
+		_, err := myBridge.UpdateSensorConfig(eachsensor.ID, settings)
+		if err != nil {
+			fmt.Printf("ERROR: Could not update sensor \"%s\": %v\n", eachsensor.Name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Updated sensor \"%s\" (%d)\n", eachsensor.Name, eachsensor.ID)
+		changed = true
+	}
+
+	return changed
+}
+
+// view or change the settings of a motion sensor
+func motionSensor(selector string) {
+	loadSensors()
+	subsensors := findMotionSensor(selector)
+
+	if configureMotionSensor(subsensors) {
+		// reload to show the new settings
+		loadSensors()
+		subsensors = findMotionSensor(selector)
+		fmt.Println()
+	}
+
+	displayMotionSensor(subsensors)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// how often the bridge is asked for a username while waiting for the link button
+const pairPollInterval time.Duration = time.Second
+
+// the error the bridge returns until its link button is pressed
+const linkButtonNotPressed int = 101
+
+// the application half of the devicetype users are created with, as in huelights#kitchen-pi
+const deviceTypeApplication string = "huelights"
+
+// the longest devicetype the bridge accepts, and the longest device name within it
+const deviceTypeLength int = 40
+const deviceNameLength int = 19
+
+// a username and optional entertainment client key created by pairing
+type pairedUser struct {
+	DeviceType string `json:"-"`
+	Username   string `json:"username"`
+	ClientKey  string `json:"clientkey"`
+}
+
+// builds a devicetype following the Hue application#device convention, using the hostname when there is no label
+func buildDeviceType(label string) (string, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", fmt.Errorf("could not read the hostname, pass a label with --createuser [label]: %v", err)
+		}
+		// a long hostname is shortened rather than refused, as it was not chosen for the bridge
+		label = strings.SplitN(hostname, ".", 2)[0]
+		if len(label) > deviceNameLength {
+			label = label[:deviceNameLength]
+		}
+	}
+
+	if strings.Contains(label, "#") {
+		return "", fmt.Errorf("label \"%s\" cannot contain #", label)
+	}
+	if len(label) > deviceNameLength {
+		return "", fmt.Errorf("label \"%s\" is %d characters, the bridge allows %d", label, len(label), deviceNameLength)
+	}
+
+	devicetype := deviceTypeApplication + "#" + label
+	if len(devicetype) > deviceTypeLength {
+		return "", fmt.Errorf("devicetype \"%s\" is %d characters, the bridge allows %d", devicetype, len(devicetype), deviceTypeLength)
+	}
+
+	return devicetype, nil
+}
+
+// asks the bridge for a new username, which only succeeds once its link button has been pressed
+func requestUser(host string, devicetype string, clientKey bool) (*pairedUser, error) {
+	body := map[string]interface{}{"devicetype": devicetype}
+	if clientKey {
+		body["generateclientkey"] = true
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := bridgeClient.Post(bridgeHostURL(host)+"/api", "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	result, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []struct {
+		Success *pairedUser  `json:"success"`
+		Error   *bridgeError `json:"error"`
+	}
+	if err := json.Unmarshal(result, &responses); err != nil {
+		return nil, fmt.Errorf("could not read bridge response: %v", err)
+	}
+
+	for _, eachresponse := range responses {
+		if eachresponse.Error != nil {
+			return nil, eachresponse.Error
+		}
+		if eachresponse.Success != nil && eachresponse.Success.Username != "" {
+			eachresponse.Success.DeviceType = devicetype
+			return eachresponse.Success, nil
+		}
+	}
+
+	return nil, errors.New("bridge did not return a username")
+}
+
+// asks for a username every second until the link button is pressed or the window runs out, showing a countdown
+func pairUser(devicetype string, window time.Duration, clientKey bool) (*pairedUser, error) {
+	if clientKey {
+		if err := checkClientKeyDestination(); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Printf("Press the link button on the Hue bridge within %s\n", window)
+
+	deadline := time.Now().Add(window)
+	for {
+		user, err := requestUser(myBridge.Host, devicetype, clientKey)
+		if err == nil {
+			fmt.Println("\nLink button pressed")
+			return user, nil
+		}
+
+		var linkerr *bridgeError
+		if !errors.As(err, &linkerr) || linkerr.Type != linkButtonNotPressed {
+			fmt.Println()
+			return nil, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			fmt.Println()
+			return nil, fmt.Errorf("the link button was not pressed within %s", window)
+		}
+
+		fmt.Printf("\rWaiting for the link button, %2ds left ", int(remaining.Round(time.Second).Seconds()))
+		time.Sleep(pairPollInterval)
+	}
+}
+
+// saves a paired username, and client key if one was generated, to the profile in use, or to its secrets file
+// when it has one, the username is never written in to a config file which keeps it elsewhere
+func saveUsername(user *pairedUser) {
+	if viper.ConfigFileUsed() == "" {
+		return
+	}
+
+	destination := viper.ConfigFileUsed()
+	if selectedProfile != "" {
+		destination = fmt.Sprintf("profile \"%s\" in %s", selectedProfile, viper.ConfigFileUsed())
+	}
+
+	var secreterr error
+	external := ""
+	err := updateConfigProfile(func(profile *bridgeProfile) {
+		if profile.BridgeID == "" {
+			profile.BridgeID = myBridgeID
+		}
+
+		if !profile.hasSecretSource() {
+			profile.Username = user.Username
+			profile.ClientKey = user.ClientKey
+			return
+		}
+
+		// a client key is as secret as the username so is kept with it in the secrets file, not the config
+		profile.ClientKey = ""
+		switch {
+		case profile.UsernameFile != "":
+			secreterr = writeSecretFile(profile.UsernameFile, user.Username, user.ClientKey)
+			destination = "secrets file " + profile.UsernameFile
+		case profile.UsernameEnv != "":
+			external = "environment variable " + profile.UsernameEnv
+		default:
+			external = "the source read by usernamecommand"
+		}
+	})
+	if err == nil {
+		err = secreterr
+	}
+	if err != nil {
+		fmt.Printf("ERROR: Unable to save the username: %v\n", err)
+		return
+	}
+
+	if external != "" {
+		fmt.Printf("WARN: The username is read from %s, update it with the new username, use --show-secrets to display it\n", external)
+		return
+	}
+
+	fmt.Printf("Saved username to %s\n", destination)
+}
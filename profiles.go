@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// a bridge and the username used to log in to it, a config file can hold several under "bridges"
+type bridgeProfile struct {
+	Bridge          string `yaml:"bridge,omitempty"`
+	BridgeID        string `yaml:"bridgeid,omitempty"`
+	Username        string `yaml:"username,omitempty"`
+	UsernameEnv     string `yaml:"usernameenv,omitempty"`
+	UsernameFile    string `yaml:"usernamefile,omitempty"`
+	UsernameCommand string `yaml:"usernamecommand,omitempty"`
+	ClientKey       string `yaml:"clientkey,omitempty"`
+	Application     string `yaml:"application,omitempty"`
+}
+
+// the profile name a config file written before profiles existed is moved to
+const legacyProfileName string = "default"
+
+// the profile in use, empty when the config file holds a single bridge
+var selectedProfile string
+
+// loads the bridge profiles from the config file
+func loadProfiles() (map[string]bridgeProfile, error) {
+	profiles := map[string]bridgeProfile{}
+	if err := viper.UnmarshalKey("bridges", &profiles); err != nil {
+		return nil, fmt.Errorf("bridges in config file are not valid: %v", err)
+	}
+	return profiles, nil
+}
+
+// returns the names of profiles sorted alphabetically
+func profileNames(profiles map[string]bridgeProfile) []string {
+	var names []string
+	for k := range profiles {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// picks a profile by --profile or the config file default and uses its bridge and username,
+// unless --bridge or --username were passed on the command line
+func selectProfile() error {
+	profiles, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	// viper lower cases keys so profile names are matched in lower case
+	name := strings.ToLower(viper.GetString("profile"))
+	if name == "" {
+		name = strings.ToLower(viper.GetString("default"))
+	}
+
+	if name == "" {
+		switch {
+		case len(profiles) < 1 || viper.GetString("bridge") != "":
+			// a config file with a single bridge
+			return nil
+		case len(profiles) == 1:
+			name = profileNames(profiles)[0]
+		default:
+			return fmt.Errorf("config file has %d bridge profiles (%s), choose one with --profile or set default", len(profiles), strings.Join(profileNames(profiles), ", "))
+		}
+	}
+
+	profile, found := profiles[name]
+	if !found {
+		if len(profiles) < 1 {
+			return fmt.Errorf("profile \"%s\" not found, config file has no bridge profiles", name)
+		}
+		return fmt.Errorf("profile \"%s\" not found, profiles are: %s", name, strings.Join(profileNames(profiles), ", "))
+	}
+
+	selectedProfile = name
+	for key, value := range map[string]string{
+		"bridge":          profile.Bridge,
+		"bridgeid":        profile.BridgeID,
+		"username":        profile.Username,
+		"usernameenv":     profile.UsernameEnv,
+		"usernamefile":    profile.UsernameFile,
+		"usernamecommand": profile.UsernameCommand,
+		"clientkey":       profile.ClientKey,
+		"application":     profile.Application,
+	} {
+		if value == "" || pflag.CommandLine.Changed(key) {
+			continue
+		}
+		viper.Set(key, value)
+	}
+
+	return nil
+}
+
+// reads a config file so it can be added to, returns false if it does not exist yet
+func readConfigFile(configFile string) (huelightConfig, bool, error) {
+	var config huelightConfig
+
+	data, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return config, false, nil
+	}
+	if err != nil {
+		return config, false, err
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, false, fmt.Errorf("\"%s\" is not a valid config file: %v", configFile, err)
+	}
+
+	return config, true, nil
+}
+
+// returns the name a profile has in the config file, which may not be lower case like viper keys
+func profileKey(config huelightConfig, name string) string {
+	for k := range config.Bridges {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+// adds or replaces a profile, moving a single bridge config in to a profile first so both are kept
+func addProfile(config *huelightConfig, name string, profile bridgeProfile) {
+	if config.Bridges == nil {
+		config.Bridges = map[string]bridgeProfile{}
+	}
+
+	if config.Bridge != "" {
+		legacy := legacyProfileName
+		if legacy == name {
+			legacy = "previous"
+		}
+		config.Bridges[legacy] = config.bridgeProfile
+		if config.Default == "" {
+			config.Default = legacy
+		}
+		config.bridgeProfile = bridgeProfile{}
+	}
+
+	config.Bridges[profileKey(*config, name)] = profile
+	if config.Default == "" {
+		config.Default = name
+	}
+}
+
+// writes a config file by writing a temporary file and renaming it, so a failed write leaves the old file intact
+func writeConfigFile(configFile string, config huelightConfig) error {
+	data, err := yaml.Marshal(&config)
+	if err != nil {
+		return fmt.Errorf("cannot generate configuration: %v", err)
+	}
+
+	temp, err := ioutil.TempFile(filepath.Dir(configFile), filepath.Base(configFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	// a rewritten file keeps its permissions
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(configFile); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), configFile)
+}
+
+// changes the profile in use, or the single bridge when there are no profiles, in the config file that was loaded
+func updateConfigProfile(update func(profile *bridgeProfile)) error {
+	configFile := viper.ConfigFileUsed()
+	config, found, err := readConfigFile(configFile)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("config file \"%s\" not found", configFile)
+	}
+
+	if selectedProfile == "" {
+		update(&config.bridgeProfile)
+	} else {
+		name := profileKey(config, selectedProfile)
+		profile, found := config.Bridges[name]
+		if !found {
+			return fmt.Errorf("profile \"%s\" not found in \"%s\"", selectedProfile, configFile)
+		}
+		update(&profile)
+		config.Bridges[name] = profile
+	}
+
+	return writeConfigFile(configFile, config)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// the environment variable a new config file's bridge is read from when running non-interactively
+const bridgeEnvVar string = "HUELIGHTS_BRIDGE"
+
+// environment variables for settings scripts need without passing flags
+var promptEnvVars = map[string]string{
+	"yes":             "HUELIGHTS_YES",
+	"non-interactive": "HUELIGHTS_NON_INTERACTIVE",
+	"config":          "HUELIGHTS_CONFIG",
+	"profile":         "HUELIGHTS_PROFILE",
+}
+
+// lets the prompt settings be set from the environment, as by provisioning scripts
+func bindPromptEnv() error {
+	for key, env := range promptEnvVars {
+		if err := viper.BindEnv(key, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// is stdin never to be read, with --yes or --non-interactive
+func nonInteractive() bool {
+	return viper.GetBool("yes") || viper.GetBool("non-interactive")
+}
+
+// a yes or no prompt for an optional offer, which is declined when non-interactive unless --yes was passed
+func offerPrompt() bool {
+	if nonInteractive() && !viper.GetBool("yes") {
+		fmt.Println("n (--non-interactive)")
+		return false
+	}
+	return yesNoPrompt()
+}
+
+// returns the answer to a question from its flag or environment variable, asking for it unless non-interactive,
+// when a missing answer is an error
+func valuePrompt(name string, question string, key string, env string) string {
+	if viper.IsSet(key) {
+		return viper.GetString(key)
+	}
+	if env != "" && os.Getenv(env) != "" {
+		return os.Getenv(env)
+	}
+
+	if nonInteractive() {
+		fmt.Printf("ERROR: %s is needed when running non-interactively, pass --%s", name, key)
+		if env != "" {
+			fmt.Printf(" or set %s", env)
+		}
+		fmt.Println()
+		os.Exit(1)
+	}
+
+	var userprompt string
+	fmt.Print(question)
+	fmt.Scanln(&userprompt)
+	return userprompt
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/amimof/huego"
+)
+
+// the classid of resourcelinks created with --createlink, distinct from automationClassID
+// so a link made by hand is never taken for an automation and deleted with it
+const resourcelinkClassID uint16 = 4647
+
+// resource types that can be linked, keyed by the name used with --links
+var linkTypes = map[string]string{
+	"light":    "lights",
+	"group":    "groups",
+	"scene":    "scenes",
+	"schedule": "schedules",
+	"rule":     "rules",
+	"sensor":   "sensors",
+}
+
+// loads all resourcelinks from the bridge sorted by ID
+func loadResourcelinks() []*huego.Resourcelink {
+	links, err := myBridge.GetResourcelinks()
+	if err != nil {
+		fmt.Println("ERROR: Could not load resourcelinks from bridge")
+		os.Exit(1)
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].ID < links[j].ID
+	})
+
+	return links
+}
+
+// find a resourcelink when given its name or ID
+func findResourcelink(links []*huego.Resourcelink, selector string) (*huego.Resourcelink, bool) {
+	if linkid, err := strconv.Atoi(selector); err == nil {
+		for _, eachlink := range links {
+			if eachlink.ID == linkid {
+				return eachlink, true
+			}
+		}
+		return nil, false
+	}
+
+	for _, eachlink := range links {
+		if strings.EqualFold(eachlink.Name, selector) {
+			return eachlink, true
+		}
+	}
+	return nil, false
+}
+
+// the resources a resourcelink can point to, loaded once so each link can be named
+type linkedResources struct {
+	scenes    []huego.Scene
+	schedules []*huego.Schedule
+	rules     []*huego.Rule
+}
+
+// loads every kind of resource that can be linked
+func loadLinkedResources() linkedResources {
+	loadGroups()
+	loadSensors()
+	return linkedResources{
+		scenes:    loadScenes(),
+		schedules: loadSchedules(),
+		rules:     loadRules(),
+	}
+}
+
+// describes a linked address such as /rules/3 as rule "Hall on"
+func (r linkedResources) describe(address string) string {
+	parts := strings.Split(strings.Trim(address, "/"), "/")
+	if len(parts) != 2 {
+		return address
+	}
+
+	name := ""
+	id, _ := strconv.Atoi(parts[1])
+
+	switch parts[0] {
+	case "lights":
+		name = getLightName(id)
+	case "groups":
+		name = getGroupName(id)
+	case "sensors":
+		if sensor, found := getSensor(id); found {
+			name = sensor.Name
+		}
+	case "scenes":
+		for _, eachscene := range r.scenes {
+			if eachscene.ID == parts[1] {
+				name = eachscene.Name
+			}
+		}
+	case "schedules":
+		if schedule, found := findSchedule(r.schedules, parts[1]); found {
+			name = schedule.Name
+		}
+	case "rules":
+		if rule, found := findRule(r.rules, parts[1]); found {
+			name = rule.Name
+		}
+	default:
+		return address
+	}
+
+	kind := strings.TrimSuffix(parts[0], "s")
+	if name == "" {
+		return fmt.Sprintf("%s %s (missing)", kind, parts[1])
+	}
+	return fmt.Sprintf("%s \"%s\"", kind, name)
+}
+
+// resolves a link such as rule:Hall on or /rules/3 to its address
+func (r linkedResources) resolve(link string) (string, error) {
+	link = strings.TrimSpace(link)
+	if strings.HasPrefix(link, "/") {
+		return link, nil
+	}
+
+	parts := strings.SplitN(link, ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("link \"%s\" must be type:name, like rule:Hall on", link)
+	}
+
+	kind, ok := linkTypes[strings.ToLower(parts[0])]
+	if !ok {
+		return "", fmt.Errorf("link type \"%s\" must be light, group, scene, schedule, rule or sensor", parts[0])
+	}
+	name := strings.TrimSpace(parts[1])
+
+	id := ""
+	switch kind {
+	case "lights":
+		if lightid, found := getLightIDFromName(name); found {
+			id = strconv.Itoa(lightid)
+		} else if lightid, err := strconv.Atoi(name); err == nil && checkLightValid(lightid) {
+			id = name
+		}
+	case "groups":
+		if groupid, found := getGroupIDFromName(name); found {
+			id = strconv.Itoa(groupid)
+		}
+	case "sensors":
+		if sensorid, found := getSensorIDFromName(name); found {
+			id = strconv.Itoa(sensorid)
+		}
+	case "scenes":
+		if scenes := findScenes(r.scenes, name); len(scenes) == 1 {
+			id = scenes[0].ID
+		}
+	case "schedules":
+		if schedule, found := findSchedule(r.schedules, name); found {
+			id = strconv.Itoa(schedule.ID)
+		}
+	case "rules":
+		if rule, found := findRule(r.rules, name); found {
+			id = strconv.Itoa(rule.ID)
+		}
+	}
+
+	if id == "" {
+		return "", fmt.Errorf("\"%s\" is not a valid %s name or id", name, strings.ToLower(parts[0]))
+	}
+
+	return fmt.Sprintf("/%s/%s", kind, id), nil
+}
+
+// display a list of all resourcelinks and the resources they link
+func listResourcelinks() {
+	resources := loadLinkedResources()
+	links := loadResourcelinks()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "ID", "Name", "Description", "Address", "Linked")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "--", "----", "-----------", "-------", "------")
+
+	for _, eachlink := range links {
+		if len(eachlink.Links) < 1 {
+			fmt.Fprintf(w, "%d\t%s\t%s\t\t\t\n", eachlink.ID, eachlink.Name, eachlink.Description)
+		}
+		for _, eachaddress := range eachlink.Links {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t\n", eachlink.ID, eachlink.Name, eachlink.Description, eachaddress, resources.describe(eachaddress))
+		}
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of resourcelinks found: %d\n", len(links))
+}
+
+// creates a resourcelink from a comma separated list of links such as rule:Hall on,sensor:Away mode
+func createResourcelink(name string, description string, linklist string) {
+	if len(name) < 1 || len(name) > bridgeNameLength {
+		fmt.Printf("ERROR: Resourcelink name \"%s\" must be between 1 and %d characters\n", name, bridgeNameLength)
+		os.Exit(1)
+	}
+
+	resources := loadLinkedResources()
+
+	var links []string
+	for _, eachlink := range strings.Split(linklist, ",") {
+		if strings.TrimSpace(eachlink) == "" {
+			continue
+		}
+		address, err := resources.resolve(eachlink)
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		links = append(links, address)
+	}
+
+	if len(links) < 1 {
+		fmt.Println("ERROR: --links must list at least one resource, like rule:Hall on,sensor:Away mode")
+		os.Exit(1)
+	}
+
+	newlink := &huego.Resourcelink{
+		Name:        name,
+		Description: description,
+		Type:        "Link",
+		ClassID:     resourcelinkClassID,
+		Links:       links,
+	}
+
+	resp, err := myBridge.CreateResourcelink(newlink)
+	if err != nil {
+		fmt.Printf("ERROR: Could not create resourcelink \"%s\": %v\n", name, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Created resourcelink \"%s\" with id %v linking:\n", name, resp.Success["id"])
+	for _, eachaddress := range links {
+		fmt.Printf("  %s\n", resources.describe(eachaddress))
+	}
+}
+
+// deletes a resourcelink, and when recursive the rules, schedules, scenes and clip sensors it links
+func deleteResourcelink(selector string, recursive bool) {
+	resources := loadLinkedResources()
+
+	link, found := findResourcelink(loadResourcelinks(), selector)
+	if !found {
+		fmt.Printf("ERROR: \"%s\" is not a valid resourcelink name or resourcelink id\n", selector)
+		os.Exit(1)
+	}
+
+	if recursive {
+		fmt.Printf("Resourcelink \"%s\" links:\n", link.Name)
+		for _, eachaddress := range link.Links {
+			fmt.Printf("  %s\n", resources.describe(eachaddress))
+		}
+		fmt.Printf("Delete resourcelink \"%s\" (%d) and its rules, schedules, scenes and CLIP sensors? [y/n]: ", link.Name, link.ID)
+	} else {
+		fmt.Printf("Delete resourcelink \"%s\" (%d)? [y/n]: ", link.Name, link.ID)
+	}
+
+	if !yesNoPrompt() {
+		fmt.Println("WARN: Aborting resourcelink delete")
+		return
+	}
+
+	if recursive {
+		removeAutomation(link)
+		return
+	}
+
+	checkErr(myBridge.DeleteResourcelink(link.ID))
+	fmt.Printf("Deleted resourcelink \"%s\"\n", link.Name)
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// group types the bridge creates itself, which are never restored
+var automaticGroupTypes = map[string]bool{
+	"Luminaire":   true,
+	"Lightsource": true,
+	"LightSource": true,
+}
+
+// something a restore could not map or create
+type restoreProblem struct {
+	Kind    string
+	Name    string
+	Problem string
+}
+
+// restores a backup on to the current bridge, remapping IDs from the old bridge to this one
+type bridgeRestore struct {
+	backup   *bridgeBackup
+	ids      map[string]map[string]string
+	problems []restoreProblem
+	created  map[string]int
+	reused   map[string]bool
+}
+
+// returns the resources of one type in a backup, sorted by ID so they are restored in their original order
+func (r *bridgeRestore) resources(kind string) ([]string, map[string]map[string]interface{}) {
+	resources := map[string]map[string]interface{}{}
+	var ids []string
+
+	for id, eachresource := range r.backup.Resources[kind] {
+		if fields, ok := eachresource.(map[string]interface{}); ok {
+			resources[id] = fields
+			ids = append(ids, id)
+		}
+	}
+
+	sort.SliceStable(ids, func(i, j int) bool {
+		if len(ids[i]) == len(ids[j]) {
+			return ids[i] < ids[j]
+		}
+		return len(ids[i]) < len(ids[j])
+	})
+
+	return ids, resources
+}
+
+// loads one type of resource as the bridge returns it, to compare with the resources in a backup
+func loadExistingResources(kind string) map[string]map[string]interface{} {
+	result, err := bridgeRequest(myBridge, "GET", kind, nil)
+	if err != nil {
+		fmt.Printf("ERROR: Could not load %s from bridge: %v\n", kind, err)
+		os.Exit(1)
+	}
+
+	existing := map[string]map[string]interface{}{}
+	if err := json.Unmarshal(result, &existing); err != nil {
+		fmt.Printf("ERROR: Could not read %s from bridge: %v\n", kind, err)
+		os.Exit(1)
+	}
+	return existing
+}
+
+// converts a value to how it reads back from json, so values from yaml and json backups compare with the bridge,
+// links are a set so are compared sorted
+func comparableValue(key string, value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return value
+	}
+
+	if list, ok := parsed.([]interface{}); ok && key == "links" {
+		sort.SliceStable(list, func(i, j int) bool {
+			return fmt.Sprint(list[i]) < fmt.Sprint(list[j])
+		})
+	}
+	return parsed
+}
+
+// finds an existing resource with the same name and remapped fields, such as one a previous restore created,
+// which is reused rather than duplicated, different resources sharing a name like "Timer" are not reused,
+// named reports whether any existing resource has the name
+func (r *bridgeRestore) findExisting(kind string, existing map[string]map[string]interface{}, name interface{}, fields map[string]interface{}) (found string, named bool) {
+	var ids []string
+	for id := range existing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if fmt.Sprint(existing[id]["name"]) != fmt.Sprint(name) || r.reused[kind+"/"+id] {
+			continue
+		}
+		named = true
+
+		same := true
+		for k, value := range fields {
+			if !reflect.DeepEqual(comparableValue(k, existing[id][k]), comparableValue(k, value)) {
+				same = false
+				break
+			}
+		}
+		if same {
+			r.reused[kind+"/"+id] = true
+			return id, true
+		}
+	}
+
+	return "", named
+}
+
+// records something that could not be restored
+func (r *bridgeRestore) problem(kind string, name interface{}, format string, a ...interface{}) {
+	r.problems = append(r.problems, restoreProblem{Kind: kind, Name: fmt.Sprint(name), Problem: fmt.Sprintf(format, a...)})
+}
+
+// maps backup lights to this bridge by uniqueid
+func (r *bridgeRestore) mapLights() {
+	ids, lights := r.resources("lights")
+	for _, id := range ids {
+		uniqueid := fmt.Sprint(lights[id]["uniqueid"])
+		if light, found := getLightFromUniqueID(uniqueid); found {
+			r.ids["lights"][id] = fmt.Sprint(light.ID)
+			continue
+		}
+		r.problem("light", lights[id]["name"], "uniqueid %s not found on this bridge", uniqueid)
+	}
+}
+
+// maps backup sensors to this bridge by uniqueid, creating CLIP sensors which do not exist yet
+func (r *bridgeRestore) mapSensors() {
+	ids, sensors := r.resources("sensors")
+	for _, id := range ids {
+		sensor := sensors[id]
+		sensortype := fmt.Sprint(sensor["type"])
+		uniqueid, _ := sensor["uniqueid"].(string)
+
+		// every bridge has a single built in daylight sensor
+		if sensortype == "Daylight" {
+			if daylight, found := getSensorByType("Daylight"); found {
+				r.ids["sensors"][id] = fmt.Sprint(daylight.ID)
+				continue
+			}
+		}
+
+		found := false
+		for _, eachsensor := range loadedSensors {
+			if uniqueid != "" && eachsensor.UniqueID == uniqueid && eachsensor.Type == sensortype {
+				r.ids["sensors"][id] = fmt.Sprint(eachsensor.ID)
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		if !strings.HasPrefix(sensortype, "CLIP") {
+			r.problem("sensor", sensor["name"], "%s with uniqueid %s not found on this bridge", sensortype, uniqueid)
+			continue
+		}
+
+		body := map[string]interface{}{}
+		for _, k := range []string{"name", "type", "modelid", "manufacturername", "swversion", "uniqueid", "state", "config"} {
+			if value, ok := sensor[k]; ok {
+				body[k] = value
+			}
+		}
+		// state and config hold read only values the bridge rejects, so only the values the sensor holds are kept
+		if state, ok := body["state"].(map[string]interface{}); ok {
+			delete(state, "lastupdated")
+		}
+		if config, ok := body["config"].(map[string]interface{}); ok {
+			delete(config, "reachable")
+			delete(config, "battery")
+		}
+
+		newid, err := createBridgeResource(myBridge, "sensors", body)
+		if err != nil {
+			r.problem("sensor", sensor["name"], "could not create: %v", err)
+			continue
+		}
+		r.ids["sensors"][id] = newid
+		r.created["sensors"]++
+	}
+}
+
+// maps a list of backup light IDs to this bridge, reporting any which cannot be mapped
+func (r *bridgeRestore) mapLightList(kind string, name interface{}, lights interface{}) []string {
+	list, _ := lights.([]interface{})
+	var mapped []string
+	for _, eachlight := range list {
+		if newid, ok := r.ids["lights"][fmt.Sprint(eachlight)]; ok {
+			mapped = append(mapped, newid)
+			continue
+		}
+		r.problem(kind, name, "light %v could not be mapped, left out", eachlight)
+	}
+	return mapped
+}
+
+// restores rooms, zones and light groups, reusing groups of the same name and type
+func (r *bridgeRestore) restoreGroups() {
+	r.ids["groups"]["0"] = "0"
+
+	ids, groups := r.resources("groups")
+	for _, id := range ids {
+		group := groups[id]
+		grouptype := fmt.Sprint(group["type"])
+		if automaticGroupTypes[grouptype] {
+			continue
+		}
+
+		for _, eachgroup := range loadedGroups {
+			if eachgroup.Name == group["name"] && eachgroup.Type == grouptype {
+				r.ids["groups"][id] = fmt.Sprint(eachgroup.ID)
+				break
+			}
+		}
+		if _, ok := r.ids["groups"][id]; ok {
+			continue
+		}
+
+		body := map[string]interface{}{
+			"name":   group["name"],
+			"type":   grouptype,
+			"lights": r.mapLightList("group", group["name"], group["lights"]),
+		}
+		if class, ok := group["class"]; ok {
+			body["class"] = class
+		}
+		if locations, ok := group["locations"].(map[string]interface{}); ok {
+			mapped := map[string]interface{}{}
+			for lightid, location := range locations {
+				if newid, ok := r.ids["lights"][lightid]; ok {
+					mapped[newid] = location
+				}
+			}
+			body["locations"] = mapped
+		}
+
+		newid, err := createBridgeResource(myBridge, "groups", body)
+		if err != nil {
+			r.problem("group", group["name"], "could not create: %v", err)
+			continue
+		}
+		r.ids["groups"][id] = newid
+		r.created["groups"]++
+	}
+}
+
+// restores scenes with their lightstates, reusing scenes of the same name in the same group
+func (r *bridgeRestore) restoreScenes() {
+	existing := loadScenes()
+
+	ids, scenes := r.resources("scenes")
+	for _, id := range ids {
+		scene := scenes[id]
+		scenetype := fmt.Sprint(scene["type"])
+
+		body := map[string]interface{}{
+			"name":    scene["name"],
+			"type":    scenetype,
+			"recycle": scene["recycle"],
+		}
+
+		if scenetype == "GroupScene" {
+			groupid, ok := r.ids["groups"][fmt.Sprint(scene["group"])]
+			if !ok {
+				r.problem("scene", scene["name"], "group %v could not be mapped", scene["group"])
+				continue
+			}
+			body["group"] = groupid
+		} else {
+			body["lights"] = r.mapLightList("scene", scene["name"], scene["lights"])
+		}
+
+		reused := false
+		for _, eachscene := range existing {
+			if eachscene.Name == scene["name"] && eachscene.Type == scenetype && (scenetype != "GroupScene" || eachscene.Group == body["group"]) {
+				r.ids["scenes"][id] = eachscene.ID
+				reused = true
+				break
+			}
+		}
+		if reused {
+			continue
+		}
+
+		if lightstates, ok := scene["lightstates"].(map[string]interface{}); ok {
+			mapped := map[string]interface{}{}
+			for lightid, state := range lightstates {
+				if newid, ok := r.ids["lights"][lightid]; ok {
+					mapped[newid] = state
+				}
+			}
+			body["lightstates"] = mapped
+		}
+
+		if appdata, ok := scene["appdata"]; ok {
+			body["appdata"] = appdata
+		}
+
+		newid, err := createBridgeResource(myBridge, "scenes", body)
+		if err != nil {
+			r.problem("scene", scene["name"], "could not create: %v", err)
+			continue
+		}
+		r.ids["scenes"][id] = newid
+		r.created["scenes"]++
+	}
+}
+
+// rewrites an address from the old bridge to this one, such as /sensors/5/state to /sensors/12/state
+func (r *bridgeRestore) remapAddress(address string) (string, bool) {
+	parts := strings.Split(strings.Trim(address, "/"), "/")
+
+	// schedules address the api with the username of whoever created them
+	prefix := ""
+	if len(parts) > 2 && parts[0] == "api" {
+		prefix = "/api/" + myBridge.User
+		parts = parts[2:]
+	}
+
+	if len(parts) >= 2 {
+		if ids, ok := r.ids[parts[0]]; ok {
+			newid, ok := ids[parts[1]]
+			if !ok {
+				return address, false
+			}
+			parts[1] = newid
+		}
+	}
+
+	return prefix + "/" + strings.Join(parts, "/"), true
+}
+
+// rewrites a command body recalling a scene from the old bridge to this one
+func (r *bridgeRestore) remapBody(body interface{}) (interface{}, bool) {
+	fields, ok := body.(map[string]interface{})
+	if !ok {
+		return body, true
+	}
+
+	if scene, ok := fields["scene"]; ok {
+		newid, ok := r.ids["scenes"][fmt.Sprint(scene)]
+		if !ok {
+			return body, false
+		}
+		fields["scene"] = newid
+	}
+
+	return fields, true
+}
+
+// remaps every address and body in a list of actions or commands, returning false if any could not be mapped
+func (r *bridgeRestore) remapActions(kind string, name interface{}, actions []interface{}) bool {
+	for _, eachaction := range actions {
+		action, ok := eachaction.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		address, ok := r.remapAddress(fmt.Sprint(action["address"]))
+		if !ok {
+			r.problem(kind, name, "address %v could not be mapped", action["address"])
+			return false
+		}
+		action["address"] = address
+
+		if body, ok := action["body"]; ok {
+			newbody, ok := r.remapBody(body)
+			if !ok {
+				r.problem(kind, name, "scene in %v could not be mapped", action["address"])
+				return false
+			}
+			action["body"] = newbody
+		}
+	}
+	return true
+}
+
+// restores schedules, reusing those with the same name and command which already exist
+func (r *bridgeRestore) restoreSchedules() {
+	existing := loadExistingResources("schedules")
+
+	ids, schedules := r.resources("schedules")
+	for _, id := range ids {
+		schedule := schedules[id]
+
+		command, _ := schedule["command"].(map[string]interface{})
+		if command == nil || !r.remapActions("schedule", schedule["name"], []interface{}{command}) {
+			continue
+		}
+
+		found, named := r.findExisting("schedules", existing, schedule["name"], map[string]interface{}{"command": command})
+		if found != "" {
+			r.ids["schedules"][id] = found
+			continue
+		}
+
+		body := map[string]interface{}{"command": command}
+		for _, k := range []string{"name", "description", "localtime", "status", "autodelete", "recycle"} {
+			if value, ok := schedule[k]; ok {
+				body[k] = value
+			}
+		}
+
+		newid, err := createBridgeResource(myBridge, "schedules", body)
+		if err != nil {
+			r.problem("schedule", schedule["name"], "could not create: %v", err)
+			continue
+		}
+		r.ids["schedules"][id] = newid
+		r.created["schedules"]++
+		if named {
+			r.problem("schedule", schedule["name"], "created as %s, an existing schedule has the same name but a different command", newid)
+		}
+	}
+}
+
+// restores rules with their condition and action addresses rewritten, reusing those with the same name, conditions
+// and actions which already exist
+func (r *bridgeRestore) restoreRules() {
+	existing := loadExistingResources("rules")
+
+	ids, rules := r.resources("rules")
+	for _, id := range ids {
+		rule := rules[id]
+
+		conditions, _ := rule["conditions"].([]interface{})
+		mapped := true
+		for _, eachcondition := range conditions {
+			condition, ok := eachcondition.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			address, ok := r.remapAddress(fmt.Sprint(condition["address"]))
+			if !ok {
+				r.problem("rule", rule["name"], "condition address %v could not be mapped", condition["address"])
+				mapped = false
+				break
+			}
+			condition["address"] = address
+		}
+
+		actions, _ := rule["actions"].([]interface{})
+		if !mapped || !r.remapActions("rule", rule["name"], actions) {
+			continue
+		}
+
+		found, named := r.findExisting("rules", existing, rule["name"], map[string]interface{}{"conditions": conditions, "actions": actions})
+		if found != "" {
+			r.ids["rules"][id] = found
+			continue
+		}
+
+		body := map[string]interface{}{
+			"name":       rule["name"],
+			"conditions": conditions,
+			"actions":    actions,
+		}
+		if status, ok := rule["status"]; ok {
+			body["status"] = status
+		}
+
+		newid, err := createBridgeResource(myBridge, "rules", body)
+		if err != nil {
+			r.problem("rule", rule["name"], "could not create: %v", err)
+			continue
+		}
+		r.ids["rules"][id] = newid
+		r.created["rules"]++
+		if named {
+			r.problem("rule", rule["name"], "created as %s, an existing rule has the same name but different conditions or actions", newid)
+		}
+	}
+}
+
+// restores resourcelinks with their links rewritten, leaving out links which could not be mapped,
+// and reusing those with the same name and links which already exist
+func (r *bridgeRestore) restoreResourcelinks() {
+	existing := loadExistingResources("resourcelinks")
+
+	ids, links := r.resources("resourcelinks")
+	for _, id := range ids {
+		link := links[id]
+
+		var mapped []string
+		list, _ := link["links"].([]interface{})
+		for _, eachlink := range list {
+			address, ok := r.remapAddress(fmt.Sprint(eachlink))
+			if !ok {
+				r.problem("resourcelink", link["name"], "link %v could not be mapped, left out", eachlink)
+				continue
+			}
+			mapped = append(mapped, address)
+		}
+
+		if len(mapped) < 1 {
+			r.problem("resourcelink", link["name"], "no links could be mapped")
+			continue
+		}
+
+		found, named := r.findExisting("resourcelinks", existing, link["name"], map[string]interface{}{"links": mapped})
+		if found != "" {
+			r.ids["resourcelinks"][id] = found
+			continue
+		}
+
+		body := map[string]interface{}{"links": mapped}
+		for _, k := range []string{"name", "description", "type", "classid", "recycle"} {
+			if value, ok := link[k]; ok {
+				body[k] = value
+			}
+		}
+
+		newid, err := createBridgeResource(myBridge, "resourcelinks", body)
+		if err != nil {
+			r.problem("resourcelink", link["name"], "could not create: %v", err)
+			continue
+		}
+		r.ids["resourcelinks"][id] = newid
+		r.created["resourcelinks"]++
+		if named {
+			r.problem("resourcelink", link["name"], "created as %s, an existing resourcelink has the same name but different links", newid)
+		}
+	}
+}
+
+// restores a backup on to the current bridge
+func restoreBridge(inputFile string) {
+	backup, err := readBackup(inputFile)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	loadGroups()
+	loadSensors()
+
+	fmt.Printf("Restoring backup of bridge %s (API %s) taken %s on to bridge %s\n", backup.BridgeID, backup.APIVersion, backup.Created, myBridge.Host)
+	fmt.Print("Groups, scenes, schedules, rules, CLIP sensors and resourcelinks missing from this bridge will be created, continue? [y/n]: ")
+	if !yesNoPrompt() {
+		fmt.Println("WARN: Aborting restore")
+		return
+	}
+
+	restore := &bridgeRestore{
+		backup:  backup,
+		ids:     map[string]map[string]string{},
+		created: map[string]int{},
+		reused:  map[string]bool{},
+	}
+	for _, kind := range backupResources {
+		restore.ids[kind] = map[string]string{}
+	}
+
+	restore.mapLights()
+	restore.mapSensors()
+	restore.restoreGroups()
+	loadGroups()
+	restore.restoreScenes()
+	restore.restoreSchedules()
+	restore.restoreRules()
+	restore.restoreResourcelinks()
+
+	fmt.Println()
+	for _, kind := range []string{"groups", "scenes", "schedules", "rules", "sensors", "resourcelinks"} {
+		fmt.Printf("Created %-14s %d\n", kind, restore.created[kind])
+	}
+
+	if len(restore.problems) < 1 {
+		fmt.Println("\nEverything was mapped")
+		return
+	}
+
+	fmt.Println()
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t\n", "Type", "Name", "Problem")
+	fmt.Fprintf(w, "%s\t%s\t%s\t\n", "----", "----", "-------")
+	for _, eachproblem := range restore.problems {
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", eachproblem.Kind, eachproblem.Name, eachproblem.Problem)
+	}
+	w.Flush()
+
+	fmt.Printf("\nNumber of problems: %d\n", len(restore.problems))
+}
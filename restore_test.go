@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestFindExisting(t *testing.T) {
+	existing := map[string]map[string]interface{}{
+		"1": {"name": "Timer", "command": map[string]interface{}{"address": "/api/user/groups/1/action", "method": "PUT", "body": map[string]interface{}{"on": true}}},
+		"2": {"name": "Timer", "command": map[string]interface{}{"address": "/api/user/groups/2/action", "method": "PUT", "body": map[string]interface{}{"on": false}}},
+		"3": {"name": "Hall", "links": []interface{}{"/rules/4", "/sensors/9"}},
+		"4": {"name": "12", "command": map[string]interface{}{"address": "/api/user/lights/1/state", "method": "PUT", "body": map[string]interface{}{"bri": 254}}},
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		found  string
+		named  bool
+	}{
+		// the schedule whose command matches is reused, not the first of the name
+		{name: "Timer", fields: map[string]interface{}{"command": map[string]interface{}{"address": "/api/user/groups/2/action", "method": "PUT", "body": map[string]interface{}{"on": false}}}, found: "2", named: true},
+		{name: "Timer", fields: map[string]interface{}{"command": map[string]interface{}{"address": "/api/user/groups/3/action", "method": "PUT", "body": map[string]interface{}{"on": true}}}, named: true},
+		// links are compared as a set, and values read from yaml compare with json
+		{name: "Hall", fields: map[string]interface{}{"links": []string{"/sensors/9", "/rules/4"}}, found: "3", named: true},
+		{name: "12", fields: map[string]interface{}{"command": map[string]interface{}{"address": "/api/user/lights/1/state", "method": "PUT", "body": map[string]interface{}{"bri": 254}}}, found: "4", named: true},
+		// a numeric name is only a name, never an ID
+		{name: "1", fields: map[string]interface{}{"command": existing["1"]["command"]}},
+		{name: "Alarm", fields: map[string]interface{}{"command": existing["1"]["command"]}},
+	}
+
+	for _, tt := range tests {
+		restore := &bridgeRestore{reused: map[string]bool{}}
+		found, named := restore.findExisting("schedules", existing, tt.name, tt.fields)
+		if found != tt.found || named != tt.named {
+			t.Errorf("findExisting(%q, %v) = %q, %t, want %q, %t", tt.name, tt.fields, found, named, tt.found, tt.named)
+		}
+	}
+
+	// an existing resource is reused once, a second backup resource the same as it is created
+	restore := &bridgeRestore{reused: map[string]bool{}}
+	fields := map[string]interface{}{"command": existing["1"]["command"]}
+	if found, _ := restore.findExisting("schedules", existing, "Timer", fields); found != "1" {
+		t.Fatalf("findExisting first match = %q, want 1", found)
+	}
+	if found, named := restore.findExisting("schedules", existing, "Timer", fields); found != "" || !named {
+		t.Errorf("findExisting second match = %q, %t, want no match of an existing name", found, named)
+	}
+}
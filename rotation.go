@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/amimof/huego"
+	"github.com/spf13/viper"
+)
+
+// checks a username works by reading the whitelist with it, which needs a valid username
+func verifyUsername(username string) error {
+	result, err := bridgeRequest(huego.New(myBridge.Host, username), "GET", "config", nil)
+	if err != nil {
+		return err
+	}
+
+	var config struct {
+		Whitelist map[string]interface{} `json:"whitelist"`
+	}
+	if err := json.Unmarshal(result, &config); err != nil {
+		return err
+	}
+
+	if _, found := config.Whitelist[username]; !found {
+		return fmt.Errorf("username is not in the bridge whitelist")
+	}
+
+	return nil
+}
+
+// pairs a new username, checks it works and saves it to the config in place of the old one, which is listed as stale
+func rotateUser(label string) {
+	if viper.ConfigFileUsed() == "" {
+		fmt.Println("ERROR: --rotateuser needs a config file to save the new username to")
+		os.Exit(1)
+	}
+
+	devicetype, err := buildDeviceType(label)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	olduser := myBridge.User
+	var oldname string
+	if olduser != "" {
+		if users, err := myBridge.GetUsers(); err == nil {
+			for _, eachuser := range users {
+				if eachuser.Username == olduser {
+					oldname = eachuser.Name
+				}
+			}
+		}
+	}
+
+	fmt.Printf("Creating user: %s\n", devicetype)
+	paired, err := pairUser(devicetype, viper.GetDuration("pairtimeout"), viper.GetBool("generateclientkey"))
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	// the config is only changed once the new username is known to work
+	if err := verifyUsername(paired.Username); err != nil {
+		fmt.Printf("ERROR: The new username does not work, the config file was not changed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Verified the new username")
+
+	saveUsername(paired)
+	bridgeLogin(paired.Username)
+
+	fmt.Printf("\nCreated User: %s\n", paired.DeviceType)
+	fmt.Printf("    Username: %s\n", maskSecret(paired.Username))
+	if paired.ClientKey != "" {
+		fmt.Printf("   ClientKey: %s\n", maskSecret(paired.ClientKey))
+	}
+
+	if olduser == "" {
+		return
+	}
+
+	if oldname == "" {
+		oldname = "unknown"
+	}
+	fmt.Printf("\nThe previous username is now stale and should be removed, it cannot be deleted through the bridge.\n")
+	fmt.Printf("Sign in at %s and remove:\n", accountAppsURL)
+	fmt.Printf("  [ ] %s, username %s\n", oldname, maskSecret(olduser))
+}
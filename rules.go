@@ -0,0 +1,751 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/amimof/huego"
+)
+
+// rules read from a yaml file, each rule is either a full "rule" or separate "when" and "then" parts
+type ruleFile struct {
+	Rules []ruleFileEntry `yaml:"rules"`
+}
+
+type ruleFileEntry struct {
+	Name string `yaml:"name"`
+	Rule string `yaml:"rule,omitempty"`
+	When string `yaml:"when,omitempty"`
+	Then string `yaml:"then,omitempty"`
+}
+
+// condition operators written as symbols in the rule dsl
+var ruleOperators = map[string]string{
+	"==": "eq",
+	">":  "gt",
+	"<":  "lt",
+}
+
+// loads all rules from the bridge sorted by ID
+func loadRules() []*huego.Rule {
+	rules, err := myBridge.GetRules()
+	if err != nil {
+		fmt.Println("ERROR: Could not load rules from bridge")
+		os.Exit(1)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+
+	return rules
+}
+
+// find a rule when given its name or ID
+func findRule(rules []*huego.Rule, selector string) (*huego.Rule, bool) {
+	if ruleid, err := strconv.Atoi(selector); err == nil {
+		for _, eachrule := range rules {
+			if eachrule.ID == ruleid {
+				return eachrule, true
+			}
+		}
+		return nil, false
+	}
+
+	for _, eachrule := range rules {
+		if strings.EqualFold(eachrule.Name, selector) {
+			return eachrule, true
+		}
+	}
+	return nil, false
+}
+
+// display a list of all rules in the rule dsl
+func listRules() {
+	loadGroups()
+	loadSensors()
+	rules := loadRules()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "ID", "Name", "Status", "Triggered", "Rule")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "--", "----", "------", "---------", "----")
+
+	for _, eachrule := range rules {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t\n", eachrule.ID, eachrule.Name, eachrule.Status, eachrule.TimesTriggered, renderRule(eachrule))
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of rules found: %d\n", len(rules))
+}
+
+// renders a rule in the rule dsl
+func renderRule(rule *huego.Rule) string {
+	var conditions []string
+	for _, eachcondition := range rule.Conditions {
+		conditions = append(conditions, renderCondition(eachcondition))
+	}
+
+	var actions []string
+	for _, eachaction := range rule.Actions {
+		actions = append(actions, renderRuleAction(eachaction))
+	}
+
+	return fmt.Sprintf("when %s then %s", strings.Join(conditions, " and "), strings.Join(actions, " and "))
+}
+
+// renders a condition such as /sensors/5/state/presence eq true as "Hall motion".presence == true
+func renderCondition(condition *huego.Condition) string {
+	subject := renderConditionAddress(condition.Address)
+
+	switch condition.Operator {
+	case "eq", "gt", "lt":
+		for symbol, operator := range ruleOperators {
+			if operator == condition.Operator {
+				return fmt.Sprintf("%s %s %s", subject, symbol, condition.Value)
+			}
+		}
+	case "dx":
+		return subject + " changed"
+	case "ddx":
+		return fmt.Sprintf("%s changed for %s", subject, describeTimer(condition.Value))
+	case "stable":
+		return fmt.Sprintf("%s stable for %s", subject, describeTimer(condition.Value))
+	case "not stable":
+		return fmt.Sprintf("%s not stable for %s", subject, describeTimer(condition.Value))
+	case "in", "not in":
+		return fmt.Sprintf("%s %s %s", subject, condition.Operator, renderTimeRange(condition.Value))
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", subject, condition.Operator, condition.Value))
+}
+
+// renders a condition address such as /sensors/5/state/presence as "Hall motion".presence
+func renderConditionAddress(address string) string {
+	if address == "/config/localtime" {
+		return "time"
+	}
+
+	parts := strings.Split(strings.Trim(address, "/"), "/")
+	if len(parts) < 4 || parts[0] != "sensors" {
+		return address
+	}
+
+	attribute := strings.Join(parts[3:], ".")
+	if parts[2] == "config" {
+		attribute = "config." + attribute
+	}
+
+	sensorid, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return address
+	}
+
+	sensor, found := getSensor(sensorid)
+	if !found {
+		return fmt.Sprintf("%d.%s", sensorid, attribute)
+	}
+
+	if sensor.Type == "Daylight" && attribute == "daylight" {
+		return "daylight"
+	}
+
+	return fmt.Sprintf("%s.%s", quoteName(sensor.Name), attribute)
+}
+
+// renders a bridge time range T22:00:00/T06:00:00 as 22:00-06:00
+func renderTimeRange(value string) string {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) < 2 {
+		return value
+	}
+	return fmt.Sprintf("%s-%s", strings.TrimSuffix(strings.TrimPrefix(parts[0], "T"), ":00"), strings.TrimSuffix(strings.TrimPrefix(parts[1], "T"), ":00"))
+}
+
+// renders a rule action such as /groups/1/action {"on":true,"bri":127} as group Hall on bri 50%
+func renderRuleAction(ruleaction *huego.RuleAction) string {
+	parts := strings.Split(strings.Trim(ruleaction.Address, "/"), "/")
+	body, _ := ruleaction.Body.(map[string]interface{})
+
+	if len(parts) == 3 && (parts[0] == "lights" || parts[0] == "groups" || parts[0] == "sensors") {
+		id, err := strconv.Atoi(parts[1])
+		if err == nil {
+			switch parts[0] {
+			case "lights":
+				return "light " + renderTarget(getLightName(id), id) + renderActionBody(body)
+			case "groups":
+				return "group " + renderTarget(getGroupName(id), id) + renderActionBody(body)
+			case "sensors":
+				name := strconv.Itoa(id)
+				if sensor, found := getSensor(id); found {
+					name = quoteName(sensor.Name)
+				}
+
+				var settings []string
+				for _, k := range sortedKeys(body) {
+					settings = append(settings, fmt.Sprintf("sensor %s.%s = %s", name, k, renderValue(body[k])))
+				}
+				return strings.Join(settings, " and ")
+			}
+		}
+	}
+
+	data, _ := json.Marshal(ruleaction.Body)
+	return fmt.Sprintf("%s %s %s", ruleaction.Method, ruleaction.Address, string(data))
+}
+
+// renders a light or group name, falling back to its ID when it no longer exists
+func renderTarget(name string, id int) string {
+	if name == "" {
+		return strconv.Itoa(id)
+	}
+	return quoteName(name)
+}
+
+// renders a light or group state body as on bri 50% scene "Relax"
+func renderActionBody(body map[string]interface{}) string {
+	rendered := ""
+	for _, k := range sortedKeys(body) {
+		switch {
+		case k == "on" && body[k] == true:
+			rendered += " on"
+		case k == "on" && body[k] == false:
+			rendered += " off"
+		case k == "bri":
+			if bri, err := strconv.ParseFloat(fmt.Sprint(body[k]), 64); err == nil {
+				rendered += fmt.Sprintf(" bri %d%%", int(math.Round(bri*100/254)))
+				continue
+			}
+			rendered += fmt.Sprintf(" bri=%s", renderValue(body[k]))
+		case k == "scene":
+			rendered += " scene " + describeScene(fmt.Sprint(body[k]))
+		default:
+			rendered += fmt.Sprintf(" %s=%s", k, renderValue(body[k]))
+		}
+	}
+	return rendered
+}
+
+// renders a value for the rule dsl
+func renderValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// quotes a name when it contains spaces
+func quoteName(name string) string {
+	if strings.ContainsAny(name, " .") || name == "" {
+		return fmt.Sprintf("\"%s\"", name)
+	}
+	return name
+}
+
+// returns the keys of a map sorted alphabetically
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splits a rule in to tokens on whitespace, keeping quoted names together
+func tokenizeRule(text string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuote := false
+
+	for _, r := range text {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			current.WriteRune(r)
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote in \"%s\"", text)
+	}
+
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+// splits tokens on the "and" keyword
+func splitOnAnd(tokens []string) [][]string {
+	var groups [][]string
+	var current []string
+	for _, eachtoken := range tokens {
+		if strings.EqualFold(eachtoken, "and") {
+			groups = append(groups, current)
+			current = nil
+			continue
+		}
+		current = append(current, eachtoken)
+	}
+	return append(groups, current)
+}
+
+// removes the quotes from a name
+func unquote(name string) string {
+	return strings.Trim(name, "\"")
+}
+
+// parses a rule written in the rule dsl, such as
+//
+//	when "Hall motion".presence == true and daylight == false then group Hall on bri 50%
+func parseRule(text string) ([]*huego.Condition, []*huego.RuleAction, error) {
+	tokens, err := tokenizeRule(text)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(tokens) < 1 || !strings.EqualFold(tokens[0], "when") {
+		return nil, nil, fmt.Errorf("rule must start with \"when\"")
+	}
+
+	then := -1
+	for i, eachtoken := range tokens {
+		if strings.EqualFold(eachtoken, "then") {
+			then = i
+			break
+		}
+	}
+
+	if then < 2 || then == len(tokens)-1 {
+		return nil, nil, fmt.Errorf("rule must be \"when <conditions> then <actions>\"")
+	}
+
+	var conditions []*huego.Condition
+	for _, eachcondition := range splitOnAnd(tokens[1:then]) {
+		condition, err := parseCondition(eachcondition)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	var actions []*huego.RuleAction
+	for _, eachaction := range splitOnAnd(tokens[then+1:]) {
+		ruleaction, err := parseRuleAction(eachaction)
+		if err != nil {
+			return nil, nil, err
+		}
+		actions = append(actions, ruleaction)
+	}
+
+	return conditions, actions, nil
+}
+
+// parses a single condition
+//
+//	"Hall motion".presence == true
+//	"Hall light level".lightlevel < 12000
+//	"Hall switch".buttonevent changed
+//	"Hall motion".presence stable for 5m
+//	daylight == false
+//	time in 22:00-06:00
+func parseCondition(tokens []string) (*huego.Condition, error) {
+	text := strings.Join(tokens, " ")
+	if len(tokens) < 2 {
+		return nil, fmt.Errorf("condition \"%s\" is incomplete", text)
+	}
+
+	if strings.EqualFold(tokens[0], "time") {
+		operator := strings.ToLower(strings.Join(tokens[1:len(tokens)-1], " "))
+		if operator != "in" && operator != "not in" {
+			return nil, fmt.Errorf("condition \"%s\" must be \"time in HH:MM-HH:MM\"", text)
+		}
+		value, err := parseTimeRange(tokens[len(tokens)-1])
+		if err != nil {
+			return nil, err
+		}
+		return &huego.Condition{Address: "/config/localtime", Operator: operator, Value: value}, nil
+	}
+
+	address, err := parseConditionAddress(tokens[0])
+	if err != nil {
+		return nil, err
+	}
+
+	condition := &huego.Condition{Address: address}
+	rest := strings.ToLower(strings.Join(tokens[1:], " "))
+
+	switch {
+	case len(tokens) == 3 && ruleOperators[tokens[1]] != "":
+		condition.Operator = ruleOperators[tokens[1]]
+		condition.Value = unquote(tokens[2])
+	case rest == "changed":
+		condition.Operator = "dx"
+	case strings.HasPrefix(rest, "changed for "):
+		condition.Operator = "ddx"
+		condition.Value, err = formatTimer(strings.TrimPrefix(rest, "changed for "))
+	case strings.HasPrefix(rest, "stable for "):
+		condition.Operator = "stable"
+		condition.Value, err = formatTimer(strings.TrimPrefix(rest, "stable for "))
+	case strings.HasPrefix(rest, "not stable for "):
+		condition.Operator = "not stable"
+		condition.Value, err = formatTimer(strings.TrimPrefix(rest, "not stable for "))
+	default:
+		return nil, fmt.Errorf("condition \"%s\" is not understood, use ==, >, <, changed, changed for, stable for or not stable for", text)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("condition \"%s\": %v", text, err)
+	}
+
+	return condition, nil
+}
+
+// parses "Hall motion".presence, "Hall motion".config.on or daylight in to a sensor address
+func parseConditionAddress(reference string) (string, error) {
+	if strings.EqualFold(reference, "daylight") {
+		sensor, found := getSensorByType("Daylight")
+		if !found {
+			return "", fmt.Errorf("no Daylight sensor found on bridge")
+		}
+		return fmt.Sprintf("/sensors/%d/state/daylight", sensor.ID), nil
+	}
+
+	name, attribute, err := splitSensorReference(reference)
+	if err != nil {
+		return "", err
+	}
+
+	sensorid, found := getSensorIDFromName(name)
+	if !found {
+		return "", fmt.Errorf("\"%s\" is not a valid sensor name or sensor id", name)
+	}
+
+	if strings.HasPrefix(attribute, "config.") {
+		return fmt.Sprintf("/sensors/%d/config/%s", sensorid, strings.TrimPrefix(attribute, "config.")), nil
+	}
+
+	return fmt.Sprintf("/sensors/%d/state/%s", sensorid, attribute), nil
+}
+
+// splits "Hall motion".presence in to Hall motion and presence
+func splitSensorReference(reference string) (string, string, error) {
+	var name, attribute string
+
+	if strings.HasPrefix(reference, "\"") {
+		end := strings.Index(reference[1:], "\"")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quote in \"%s\"", reference)
+		}
+		name = reference[1 : end+1]
+		attribute = strings.TrimPrefix(reference[end+2:], ".")
+	} else {
+		parts := strings.SplitN(reference, ".", 2)
+		name = parts[0]
+		if len(parts) == 2 {
+			attribute = parts[1]
+		}
+	}
+
+	if name == "" || attribute == "" {
+		return "", "", fmt.Errorf("\"%s\" must be a sensor and attribute, like \"Hall motion\".presence", reference)
+	}
+
+	return name, attribute, nil
+}
+
+// parses 22:00-06:00 in to the bridge time range T22:00:00/T06:00:00
+func parseTimeRange(value string) (string, error) {
+	parts := strings.SplitN(value, "-", 2)
+	if len(parts) < 2 {
+		return "", fmt.Errorf("\"%s\" must be a time range like 22:00-06:00", value)
+	}
+
+	from, err := parseClock(parts[0])
+	if err != nil {
+		return "", err
+	}
+
+	to, err := parseClock(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("T%s/T%s", from, to), nil
+}
+
+// parses a single action
+//
+//	group Hall on bri 50%
+//	light "Desk lamp" off
+//	group Lounge scene Relax
+//	sensor "Away mode".flag = true
+func parseRuleAction(tokens []string) (*huego.RuleAction, error) {
+	text := strings.Join(tokens, " ")
+	if len(tokens) < 2 {
+		return nil, fmt.Errorf("action \"%s\" is incomplete", text)
+	}
+
+	ruleaction := &huego.RuleAction{Method: "PUT"}
+
+	switch strings.ToLower(tokens[0]) {
+	case "light":
+		lightid, found := getLightIDFromName(unquote(tokens[1]))
+		if !found {
+			if id, err := strconv.Atoi(tokens[1]); err == nil && checkLightValid(id) {
+				lightid, found = id, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("\"%s\" is not a valid light name or light id", unquote(tokens[1]))
+		}
+		ruleaction.Address = fmt.Sprintf("/lights/%d/state", lightid)
+
+	case "group":
+		groupid, found := getGroupIDFromName(unquote(tokens[1]))
+		if !found {
+			return nil, fmt.Errorf("\"%s\" is not a valid group name or group id", unquote(tokens[1]))
+		}
+		ruleaction.Address = fmt.Sprintf("/groups/%d/action", groupid)
+
+	case "sensor":
+		if len(tokens) != 4 || tokens[2] != "=" {
+			return nil, fmt.Errorf("action \"%s\" must be like sensor \"Away mode\".flag = true", text)
+		}
+		name, attribute, err := splitSensorReference(tokens[1])
+		if err != nil {
+			return nil, err
+		}
+		sensorid, found := getSensorIDFromName(name)
+		if !found {
+			return nil, fmt.Errorf("\"%s\" is not a valid sensor name or sensor id", name)
+		}
+		ruleaction.Address = fmt.Sprintf("/sensors/%d/state", sensorid)
+		ruleaction.Body = map[string]interface{}{attribute: parseValue(tokens[3])}
+		return ruleaction, nil
+
+	default:
+		return nil, fmt.Errorf("action \"%s\" must start with light, group or sensor", text)
+	}
+
+	body, err := parseActionBody(tokens[2:])
+	if err != nil {
+		return nil, fmt.Errorf("action \"%s\": %v", text, err)
+	}
+	ruleaction.Body = body
+
+	return ruleaction, nil
+}
+
+// parses on, off, bri 50%, ct 300, scene Relax and key=value settings of an action
+func parseActionBody(tokens []string) (map[string]interface{}, error) {
+	body := map[string]interface{}{}
+
+	for i := 0; i < len(tokens); i++ {
+		eachtoken := strings.ToLower(tokens[i])
+
+		switch {
+		case eachtoken == "on":
+			body["on"] = true
+		case eachtoken == "off":
+			body["on"] = false
+		case eachtoken == "bri" || eachtoken == "ct" || eachtoken == "scene":
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("%s needs a value", eachtoken)
+			}
+			i++
+			value := unquote(tokens[i])
+
+			switch eachtoken {
+			case "bri":
+				bri, err := parseBrightness(value)
+				if err != nil {
+					return nil, err
+				}
+				body["bri"] = bri
+			case "ct":
+				ct, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("ct \"%s\" must be a number", value)
+				}
+				body["ct"] = ct
+			case "scene":
+				scenes := findScenes(loadScenes(), value)
+				if len(scenes) != 1 {
+					return nil, fmt.Errorf("scene \"%s\" must match exactly one scene, found %d", value, len(scenes))
+				}
+				body["scene"] = scenes[0].ID
+			}
+		case strings.Contains(eachtoken, "="):
+			parts := strings.SplitN(tokens[i], "=", 2)
+			body[parts[0]] = parseValue(parts[1])
+		default:
+			return nil, fmt.Errorf("\"%s\" is not understood", tokens[i])
+		}
+	}
+
+	if len(body) < 1 {
+		return nil, fmt.Errorf("nothing to do")
+	}
+
+	return body, nil
+}
+
+// parses a brightness of 50% or 127 in to the bridge range 1-254
+func parseBrightness(value string) (int, error) {
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return 0, fmt.Errorf("bri \"%s\" must be between 0%% and 100%%", value)
+		}
+		return int(math.Max(1, math.Round(percent*254/100))), nil
+	}
+
+	bri, err := strconv.Atoi(value)
+	if err != nil || bri < 1 || bri > 254 {
+		return 0, fmt.Errorf("bri \"%s\" must be between 1 and 254, or a percentage", value)
+	}
+	return bri, nil
+}
+
+// parses a json value such as true, 12 or "text", anything else is treated as a string
+func parseValue(value string) interface{} {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+		return parsed
+	}
+	return unquote(value)
+}
+
+// builds a rule from the rule dsl, checking it can be created
+func compileRule(name string, text string) (*huego.Rule, error) {
+	conditions, actions, err := parseRule(text)
+	if err != nil {
+		return nil, fmt.Errorf("rule \"%s\": %v", name, err)
+	}
+
+	if len(name) < 1 || len(name) > 32 {
+		return nil, fmt.Errorf("rule name \"%s\" must be between 1 and 32 characters", name)
+	}
+
+	return &huego.Rule{
+		Name:       name,
+		Conditions: conditions,
+		Actions:    actions,
+	}, nil
+}
+
+// creates a rule on the bridge from the rule dsl
+func createRule(name string, text string) {
+	loadGroups()
+	loadSensors()
+
+	newrule, err := compileRule(name, text)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	resp, err := myBridge.CreateRule(newrule)
+	if err != nil {
+		fmt.Printf("ERROR: Could not create rule \"%s\": %v\n", name, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Created rule \"%s\" with id %v: %s\n", name, resp.Success["id"], renderRule(newrule))
+}
+
+// creates every rule in a yaml rule file, checking them all first so a bad rule creates nothing,
+// and removing the rules already created when the bridge refuses one
+func createRulesFromFile(inputFile string) {
+	yamlData, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		fmt.Printf("ERROR: Unable to read file: %s\n", inputFile)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var rules ruleFile
+	if err := yaml.Unmarshal(yamlData, &rules); err != nil {
+		fmt.Printf("ERROR: File \"%s\" is not a valid rule file: %v\n", inputFile, err)
+		os.Exit(1)
+	}
+
+	loadGroups()
+	loadSensors()
+
+	var newrules []*huego.Rule
+	failed := 0
+	for _, eachrule := range rules.Rules {
+		text := eachrule.Rule
+		if text == "" {
+			text = fmt.Sprintf("when %s then %s", eachrule.When, eachrule.Then)
+		}
+
+		newrule, err := compileRule(eachrule.Name, text)
+		if err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			failed++
+			continue
+		}
+		newrules = append(newrules, newrule)
+	}
+
+	if failed > 0 {
+		fmt.Printf("ERROR: %d of %d rules in \"%s\" are not valid, no rules were created\n", failed, len(rules.Rules), inputFile)
+		os.Exit(1)
+	}
+
+	var created []string
+	for _, eachrule := range newrules {
+		resp, err := myBridge.CreateRule(eachrule)
+		if err != nil {
+			fmt.Printf("ERROR: Could not create rule \"%s\": %v\n", eachrule.Name, err)
+			if len(created) > 0 {
+				fmt.Printf("Removing the %d rules already created from \"%s\"\n", len(created), inputFile)
+				removeLinkedResources(created)
+			}
+			os.Exit(1)
+		}
+		created = append(created, fmt.Sprintf("/rules/%v", resp.Success["id"]))
+		fmt.Printf("Created rule \"%s\" with id %v: %s\n", eachrule.Name, resp.Success["id"], renderRule(eachrule))
+	}
+}
+
+// deletes a rule
+func deleteRule(selector string) {
+	rule, found := findRule(loadRules(), selector)
+	if !found {
+		fmt.Printf("ERROR: \"%s\" is not a valid rule name or rule id\n", selector)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Delete rule \"%s\" (%d)? [y/n]: ", rule.Name, rule.ID)
+	if !yesNoPrompt() {
+		fmt.Println("WARN: Aborting rule delete")
+		return
+	}
+
+	checkErr(myBridge.DeleteRule(rule.ID))
+	fmt.Printf("Deleted rule \"%s\"\n", rule.Name)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/amimof/huego"
+)
+
+// loads a small bridge for the rule parsers to resolve names against
+func loadTestBridge(t *testing.T) {
+	t.Helper()
+
+	lights, groups, sensors := loadedLights, loadedGroups, loadedSensors
+	t.Cleanup(func() { loadedLights, loadedGroups, loadedSensors = lights, groups, sensors })
+
+	loadedLights = []huego.Light{{ID: 1, Name: "Desk lamp"}}
+	loadedGroups = []huego.Group{{ID: 2, Name: "Hall"}}
+	loadedSensors = []huego.Sensor{
+		{ID: 1, Name: "Daylight", Type: "Daylight"},
+		{ID: 5, Name: "Hall motion", Type: "ZLLPresence"},
+		{ID: 7, Name: "Away mode", Type: "CLIPGenericFlag"},
+	}
+}
+
+func TestTokenizeRule(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+		err   string
+	}{
+		{
+			input: `when "Hall motion".presence == true then group Hall on`,
+			want:  []string{"when", `"Hall motion".presence`, "==", "true", "then", "group", "Hall", "on"},
+		},
+		{input: "when\tdaylight  ==\nfalse", want: []string{"when", "daylight", "==", "false"}},
+		{input: `light "Desk  lamp" off`, want: []string{"light", `"Desk  lamp"`, "off"}},
+		{input: "", want: nil},
+		{input: `light "Desk lamp off`, err: `unterminated quote in "light "Desk lamp off"`},
+	}
+
+	for _, tt := range tests {
+		got, err := tokenizeRule(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("tokenizeRule(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("tokenizeRule(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenizeRule(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	loadTestBridge(t)
+
+	conditions, actions, err := parseRule(`when "Hall motion".presence == true and daylight == false then group Hall on bri 50% and light "Desk lamp" off`)
+	if err != nil {
+		t.Fatalf("parseRule unexpected error: %v", err)
+	}
+
+	wantConditions := []*huego.Condition{
+		{Address: "/sensors/5/state/presence", Operator: "eq", Value: "true"},
+		{Address: "/sensors/1/state/daylight", Operator: "eq", Value: "false"},
+	}
+	if !reflect.DeepEqual(conditions, wantConditions) {
+		t.Errorf("parseRule conditions = %s, want %s", prettyPrint(conditions), prettyPrint(wantConditions))
+	}
+
+	wantActions := []*huego.RuleAction{
+		{Address: "/groups/2/action", Method: "PUT", Body: map[string]interface{}{"on": true, "bri": 127}},
+		{Address: "/lights/1/state", Method: "PUT", Body: map[string]interface{}{"on": false}},
+	}
+	if !reflect.DeepEqual(actions, wantActions) {
+		t.Errorf("parseRule actions = %s, want %s", prettyPrint(actions), prettyPrint(wantActions))
+	}
+
+	errors := map[string]string{
+		"group Hall on":                              `rule must start with "when"`,
+		"":                                           `rule must start with "when"`,
+		"when then group Hall on":                    `rule must be "when <conditions> then <actions>"`,
+		"when daylight == false then":                `rule must be "when <conditions> then <actions>"`,
+		"when daylight == false group Hall on":       `rule must be "when <conditions> then <actions>"`,
+		"when daylight is false then group Hall":     `condition "daylight is false" is not understood, use ==, >, <, changed, changed for, stable for or not stable for`,
+		"when daylight == false then group Attic on": `"Attic" is not a valid group name or group id`,
+		`when "Hall motion.presence == true then group Hall on`: `unterminated quote in "when "Hall motion.presence == true then group Hall on"`,
+	}
+
+	for input, want := range errors {
+		if _, _, err := parseRule(input); err == nil || err.Error() != want {
+			t.Errorf("parseRule(%q) error = %v, want %q", input, err, want)
+		}
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	loadTestBridge(t)
+
+	tests := []struct {
+		tokens []string
+		want   huego.Condition
+		err    string
+	}{
+		{tokens: []string{`"Hall motion".presence`, "==", "true"}, want: huego.Condition{Address: "/sensors/5/state/presence", Operator: "eq", Value: "true"}},
+		{tokens: []string{`"Hall motion".lightlevel`, "<", "12000"}, want: huego.Condition{Address: "/sensors/5/state/lightlevel", Operator: "lt", Value: "12000"}},
+		{tokens: []string{`"Hall motion".temperature`, ">", "2000"}, want: huego.Condition{Address: "/sensors/5/state/temperature", Operator: "gt", Value: "2000"}},
+		{tokens: []string{`"Away mode".status`, "==", `"away"`}, want: huego.Condition{Address: "/sensors/7/state/status", Operator: "eq", Value: "away"}},
+		{tokens: []string{`"Hall motion".config.on`, "==", "true"}, want: huego.Condition{Address: "/sensors/5/config/on", Operator: "eq", Value: "true"}},
+		{tokens: []string{"5.presence", "==", "true"}, want: huego.Condition{Address: "/sensors/5/state/presence", Operator: "eq", Value: "true"}},
+		{tokens: []string{"daylight", "==", "false"}, want: huego.Condition{Address: "/sensors/1/state/daylight", Operator: "eq", Value: "false"}},
+		{tokens: []string{`"Hall motion".lastupdated`, "changed"}, want: huego.Condition{Address: "/sensors/5/state/lastupdated", Operator: "dx"}},
+		{tokens: []string{`"Hall motion".presence`, "changed", "for", "5m"}, want: huego.Condition{Address: "/sensors/5/state/presence", Operator: "ddx", Value: "PT00:05:00"}},
+		{tokens: []string{`"Hall motion".presence`, "stable", "for", "10m"}, want: huego.Condition{Address: "/sensors/5/state/presence", Operator: "stable", Value: "PT00:10:00"}},
+		{tokens: []string{`"Hall motion".presence`, "not", "stable", "for", "1m"}, want: huego.Condition{Address: "/sensors/5/state/presence", Operator: "not stable", Value: "PT00:01:00"}},
+		{tokens: []string{"time", "in", "22:00-06:00"}, want: huego.Condition{Address: "/config/localtime", Operator: "in", Value: "T22:00:00/T06:00:00"}},
+		{tokens: []string{"time", "not", "in", "7:00-9:30"}, want: huego.Condition{Address: "/config/localtime", Operator: "not in", Value: "T07:00:00/T09:30:00"}},
+		{tokens: []string{"daylight"}, err: `condition "daylight" is incomplete`},
+		{tokens: []string{"time", "at", "22:00-06:00"}, err: `condition "time at 22:00-06:00" must be "time in HH:MM-HH:MM"`},
+		{tokens: []string{"time", "in", "22:00"}, err: `"22:00" must be a time range like 22:00-06:00`},
+		{tokens: []string{"time", "in", "22:00-late"}, err: `"late" is not a valid time, use HH:MM`},
+		{tokens: []string{`"Garage".presence`, "==", "true"}, err: `"Garage" is not a valid sensor name or sensor id`},
+		{tokens: []string{"9.presence", "==", "true"}, err: `"9" is not a valid sensor name or sensor id`},
+		{tokens: []string{`"Hall motion"`, "==", "true"}, err: `""Hall motion"" must be a sensor and attribute, like "Hall motion".presence`},
+		{tokens: []string{`"Hall motion.presence`, "==", "true"}, err: `unterminated quote in ""Hall motion.presence"`},
+		{tokens: []string{`"Hall motion".presence`, "is", "true"}, err: `condition ""Hall motion".presence is true" is not understood, use ==, >, <, changed, changed for, stable for or not stable for`},
+		{tokens: []string{`"Hall motion".presence`, "stable", "for", "forever"}, err: `condition ""Hall motion".presence stable for forever": "forever" is not a valid duration`},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCondition(tt.tokens)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parseCondition(%q) error = %v, want %q", tt.tokens, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCondition(%q) unexpected error: %v", tt.tokens, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("parseCondition(%q) = %+v, want %+v", tt.tokens, *got, tt.want)
+		}
+	}
+}
+
+func TestParseConditionNoDaylight(t *testing.T) {
+	loadTestBridge(t)
+	loadedSensors = loadedSensors[1:]
+
+	if _, err := parseCondition([]string{"daylight", "==", "false"}); err == nil || err.Error() != "no Daylight sensor found on bridge" {
+		t.Errorf("parseCondition without a Daylight sensor error = %v", err)
+	}
+}
+
+func TestParseRuleAction(t *testing.T) {
+	loadTestBridge(t)
+
+	tests := []struct {
+		tokens []string
+		want   huego.RuleAction
+		err    string
+	}{
+		{tokens: []string{"light", `"Desk lamp"`, "off"}, want: huego.RuleAction{Address: "/lights/1/state", Method: "PUT", Body: map[string]interface{}{"on": false}}},
+		{tokens: []string{"light", "1", "on"}, want: huego.RuleAction{Address: "/lights/1/state", Method: "PUT", Body: map[string]interface{}{"on": true}}},
+		{tokens: []string{"group", "Hall", "on", "bri", "50%", "ct", "300"}, want: huego.RuleAction{Address: "/groups/2/action", Method: "PUT", Body: map[string]interface{}{"on": true, "bri": 127, "ct": 300}}},
+		{tokens: []string{"GROUP", "hall", "bri", "200", "transitiontime=4", "alert=select"}, want: huego.RuleAction{Address: "/groups/2/action", Method: "PUT", Body: map[string]interface{}{"bri": 200, "transitiontime": float64(4), "alert": "select"}}},
+		{tokens: []string{"sensor", `"Away mode".flag`, "=", "true"}, want: huego.RuleAction{Address: "/sensors/7/state", Method: "PUT", Body: map[string]interface{}{"flag": true}}},
+		{tokens: []string{"sensor", "7.status", "=", "2"}, want: huego.RuleAction{Address: "/sensors/7/state", Method: "PUT", Body: map[string]interface{}{"status": float64(2)}}},
+		{tokens: []string{"group"}, err: `action "group" is incomplete`},
+		{tokens: []string{"scene", "Relax"}, err: `action "scene Relax" must start with light, group or sensor`},
+		{tokens: []string{"light", "Lamp", "on"}, err: `"Lamp" is not a valid light name or light id`},
+		{tokens: []string{"light", "3", "on"}, err: `"3" is not a valid light name or light id`},
+		{tokens: []string{"group", "Attic", "on"}, err: `"Attic" is not a valid group name or group id`},
+		{tokens: []string{"group", "Hall"}, err: `action "group Hall": nothing to do`},
+		{tokens: []string{"group", "Hall", "bri"}, err: `action "group Hall bri": bri needs a value`},
+		{tokens: []string{"group", "Hall", "bri", "300"}, err: `action "group Hall bri 300": bri "300" must be between 1 and 254, or a percentage`},
+		{tokens: []string{"group", "Hall", "ct", "warm"}, err: `action "group Hall ct warm": ct "warm" must be a number`},
+		{tokens: []string{"group", "Hall", "dance"}, err: `action "group Hall dance": "dance" is not understood`},
+		{tokens: []string{"sensor", `"Away mode".flag`, "true"}, err: `action "sensor "Away mode".flag true" must be like sensor "Away mode".flag = true`},
+		{tokens: []string{"sensor", "Garage.flag", "=", "true"}, err: `"Garage" is not a valid sensor name or sensor id`},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRuleAction(tt.tokens)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parseRuleAction(%q) error = %v, want %q", tt.tokens, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRuleAction(%q) unexpected error: %v", tt.tokens, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("parseRuleAction(%q) = %s, want %s", tt.tokens, prettyPrint(got), prettyPrint(tt.want))
+		}
+	}
+}
+
+func TestParseBrightness(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+		err   string
+	}{
+		{input: "50%", want: 127},
+		{input: "100%", want: 254},
+		{input: "0%", want: 1},
+		{input: "12.5%", want: 32},
+		{input: "1", want: 1},
+		{input: "254", want: 254},
+		{input: "101%", err: `bri "101%" must be between 0% and 100%`},
+		{input: "-5%", err: `bri "-5%" must be between 0% and 100%`},
+		{input: "half%", err: `bri "half%" must be between 0% and 100%`},
+		{input: "0", err: `bri "0" must be between 1 and 254, or a percentage`},
+		{input: "255", err: `bri "255" must be between 1 and 254, or a percentage`},
+		{input: "bright", err: `bri "bright" must be between 1 and 254, or a percentage`},
+	}
+
+	for _, tt := range tests {
+		got, err := parseBrightness(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parseBrightness(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBrightness(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseBrightness(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/amimof/huego"
+)
+
+// a scene flagged by the scene audit
+type sceneProblem struct {
+	Scene   huego.Scene
+	Problem string
+}
+
+// finds orphaned and duplicate scenes
+func auditScenes() []sceneProblem {
+	loadGroups()
+	scenes := loadScenes()
+
+	allusers, err := myBridge.GetUsers()
+	checkErr(err)
+
+	knownUsers := map[string]bool{}
+	for _, eachuser := range allusers {
+		knownUsers[eachuser.Username] = true
+	}
+
+	var problems []sceneProblem
+	var remaining []huego.Scene
+
+	for _, eachscene := range scenes {
+		var found []string
+
+		for _, eachlightid := range eachscene.Lights {
+			lightid, err := strconv.Atoi(eachlightid)
+			if err != nil || !checkLightValid(lightid) {
+				found = append(found, fmt.Sprintf("references deleted light %s", eachlightid))
+			}
+		}
+
+		if eachscene.Type == "GroupScene" {
+			groupid, err := strconv.Atoi(eachscene.Group)
+			if err != nil || getGroupName(groupid) == "" {
+				found = append(found, fmt.Sprintf("group %s no longer exists", eachscene.Group))
+			}
+		}
+
+		if eachscene.Owner != "" && !knownUsers[eachscene.Owner] {
+			found = append(found, "owner is no longer a whitelist user")
+		}
+
+		for _, eachproblem := range found {
+			problems = append(problems, sceneProblem{Scene: eachscene, Problem: eachproblem})
+		}
+
+		if len(found) == 0 {
+			remaining = append(remaining, eachscene)
+		}
+	}
+
+	problems = append(problems, findDuplicateScenes(remaining)...)
+
+	return problems
+}
+
+// finds scenes with identical lightstates in the same group, keeping the most recently updated
+func findDuplicateScenes(scenes []huego.Scene) []sceneProblem {
+	var problems []sceneProblem
+
+	// newest first so the scene kept is the most recently updated
+	sort.SliceStable(scenes, func(i, j int) bool {
+		return scenes[i].LastUpdated > scenes[j].LastUpdated
+	})
+
+	kept := map[string]huego.Scene{}
+
+	for _, eachscene := range scenes {
+		// lightstates are only returned when requesting a single scene
+		fullscene, err := myBridge.GetScene(eachscene.ID)
+		if err != nil {
+			fmt.Printf("WARN: Could not load scene \"%s\" (%s): %v\n", eachscene.Name, eachscene.ID, err)
+			continue
+		}
+
+		// json sorts map keys so identical lightstates produce identical keys
+		states, err := json.Marshal(fullscene.LightStates)
+		checkErr(err)
+		key := eachscene.Type + "/" + eachscene.Group + "/" + string(states)
+
+		if original, ok := kept[key]; ok {
+			problems = append(problems, sceneProblem{Scene: eachscene, Problem: fmt.Sprintf("duplicate of \"%s\" (%s)", original.Name, original.ID)})
+			continue
+		}
+		kept[key] = eachscene
+	}
+
+	return problems
+}
+
+// display the results of a scene audit, optionally deleting the flagged scenes
+func displaySceneAudit(prune bool) {
+	problems := auditScenes()
+
+	if len(problems) < 1 {
+		fmt.Println("No orphaned or duplicate scenes found")
+		return
+	}
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", "ID", "Name", "Locked", "Problem")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", "--", "----", "------", "-------")
+
+	flagged := map[string]huego.Scene{}
+	for _, eachproblem := range problems {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t\n", eachproblem.Scene.ID, eachproblem.Scene.Name, eachproblem.Scene.Locked, eachproblem.Problem)
+		flagged[eachproblem.Scene.ID] = eachproblem.Scene
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of scenes flagged: %d\n", len(flagged))
+
+	if !prune {
+		return
+	}
+
+	fmt.Printf("\nDelete %d flagged scenes? [y/n]: ", len(flagged))
+	if !yesNoPrompt() {
+		fmt.Println("WARN: Aborting scene prune")
+		return
+	}
+
+	var sceneids []string
+	for k := range flagged {
+		sceneids = append(sceneids, k)
+	}
+	sort.Strings(sceneids)
+
+	deleted := 0
+	for _, eachid := range sceneids {
+		// locked scenes are in use by a rule or schedule and cannot be deleted
+		if flagged[eachid].Locked {
+			fmt.Printf("WARN: Scene \"%s\" (%s) is locked by a rule or schedule, skipping\n", flagged[eachid].Name, eachid)
+			continue
+		}
+
+		if err := myBridge.DeleteScene(eachid); err != nil {
+			fmt.Printf("ERROR: Could not delete scene \"%s\" (%s): %v\n", flagged[eachid].Name, eachid, err)
+			continue
+		}
+		deleted++
+	}
+
+	fmt.Printf("Deleted %d scenes\n", deleted)
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/amimof/huego"
+)
+
+const sceneArchiveVersion int = 1
+
+// portable collection of scenes, lights are referenced by name and uniqueid rather than bridge light ID
+type sceneArchive struct {
+	Application string            `yaml:"application"`
+	Version     int               `yaml:"version"`
+	Scenes      []sceneDefinition `yaml:"scenes"`
+}
+
+type sceneDefinition struct {
+	Name           string       `yaml:"name"`
+	Type           string       `yaml:"type"`
+	Group          string       `yaml:"group,omitempty"`
+	TransitionTime uint16       `yaml:"transitiontime,omitempty"`
+	Lights         []sceneLight `yaml:"lights"`
+}
+
+type sceneLight struct {
+	Name     string          `yaml:"name"`
+	UniqueID string          `yaml:"uniqueid"`
+	State    sceneLightState `yaml:"state"`
+}
+
+type sceneLightState struct {
+	On             bool      `yaml:"on"`
+	Bri            uint8     `yaml:"bri,omitempty"`
+	Hue            uint16    `yaml:"hue,omitempty"`
+	Sat            uint8     `yaml:"sat,omitempty"`
+	Xy             []float32 `yaml:"xy,omitempty,flow"`
+	Ct             uint16    `yaml:"ct,omitempty"`
+	Effect         string    `yaml:"effect,omitempty"`
+	TransitionTime uint16    `yaml:"transitiontime,omitempty"`
+}
+
+// loads all scenes from the bridge sorted by name
+func loadScenes() []huego.Scene {
+	scenes, err := myBridge.GetScenes()
+	if err != nil {
+		fmt.Println("ERROR: Could not load scenes from bridge")
+		os.Exit(1)
+	}
+
+	sort.SliceStable(scenes, func(i, j int) bool {
+		if scenes[i].Name == scenes[j].Name {
+			return scenes[i].ID < scenes[j].ID
+		}
+		return scenes[i].Name < scenes[j].Name
+	})
+
+	return scenes
+}
+
+// display a list of all scenes
+func listScenes() {
+	loadGroups()
+	scenes := loadScenes()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n", "ID", "Name", "Type", "Group", "Lights", "LastUpdated")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n", "--", "----", "----", "-----", "------", "-----------")
+
+	for _, eachscene := range scenes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t\n", eachscene.ID, eachscene.Name, eachscene.Type, sceneGroupName(eachscene), len(eachscene.Lights), eachscene.LastUpdated)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of scenes found: %d\n", len(scenes))
+}
+
+// returns the name of the group a scene belongs to, or its ID if the group cannot be found
+func sceneGroupName(scene huego.Scene) string {
+	if scene.Group == "" {
+		return ""
+	}
+	groupid, err := strconv.Atoi(scene.Group)
+	if err != nil {
+		return scene.Group
+	}
+	if name := getGroupName(groupid); name != "" {
+		return name
+	}
+	return scene.Group
+}
+
+// find scenes matching a scene name or ID, "all" returns every scene
+func findScenes(scenes []huego.Scene, selector string) []huego.Scene {
+	if strings.EqualFold(selector, "all") {
+		return scenes
+	}
+
+	var found []huego.Scene
+	for _, eachscene := range scenes {
+		if eachscene.ID == selector || strings.EqualFold(eachscene.Name, selector) {
+			found = append(found, eachscene)
+		}
+	}
+	return found
+}
+
+// converts a scene on the bridge into its portable definition
+func sceneToDefinition(sceneID string) sceneDefinition {
+	// lightstates are only returned when requesting a single scene
+	scene, err := myBridge.GetScene(sceneID)
+	checkErr(err)
+
+	definition := sceneDefinition{
+		Name:           scene.Name,
+		Type:           scene.Type,
+		TransitionTime: scene.TransitionTime,
+	}
+
+	if scene.Type == "GroupScene" {
+		definition.Group = sceneGroupName(*scene)
+	}
+
+	for _, eachlightid := range scene.Lights {
+		lightid, err := strconv.Atoi(eachlightid)
+		if err != nil {
+			continue
+		}
+
+		newlight := sceneLight{Name: getLightName(lightid)}
+		for _, eachlight := range loadedLights {
+			if eachlight.ID == lightid {
+				newlight.UniqueID = eachlight.UniqueID
+			}
+		}
+
+		if state, ok := scene.LightStates[lightid]; ok {
+			newlight.State = sceneLightState{
+				On:             state.On,
+				Bri:            state.Bri,
+				Hue:            state.Hue,
+				Sat:            state.Sat,
+				Xy:             state.Xy,
+				Ct:             state.Ct,
+				Effect:         state.Effect,
+				TransitionTime: state.TransitionTime,
+			}
+		}
+
+		definition.Lights = append(definition.Lights, newlight)
+	}
+
+	return definition
+}
+
+// writes yaml to a file, or to stdout when no file is given
+func writeYAML(data interface{}, outputFile string) {
+	yamlData, err := yaml.Marshal(data)
+	if err != nil {
+		fmt.Printf("ERROR: Cannot generate yaml. %v\n", err)
+		os.Exit(1)
+	}
+
+	if outputFile == "" {
+		fmt.Print(string(yamlData))
+		return
+	}
+
+	err = ioutil.WriteFile(outputFile, yamlData, 0644)
+	if err != nil {
+		fmt.Printf("ERROR: Unable to save into the file: %s\n", outputFile)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Saved to file: %s\n", outputFile)
+}
+
+// export one or all scenes as yaml
+func exportScenes(selector string, outputFile string) {
+	loadGroups()
+	scenes := findScenes(loadScenes(), selector)
+
+	if len(scenes) < 1 {
+		fmt.Printf("ERROR: \"--exportscenes %s\" did not match any scene name or scene id\n", selector)
+		os.Exit(1)
+	}
+
+	archive := sceneArchive{
+		Application: applicationName,
+		Version:     sceneArchiveVersion,
+	}
+
+	for _, eachscene := range scenes {
+		archive.Scenes = append(archive.Scenes, sceneToDefinition(eachscene.ID))
+	}
+
+	writeYAML(&archive, outputFile)
+}
+
+// import scenes from a yaml file, remapping lights by uniqueid
+func importScenes(inputFile string) {
+	yamlData, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		fmt.Printf("ERROR: Unable to read file: %s\n", inputFile)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var archive sceneArchive
+	if err := yaml.Unmarshal(yamlData, &archive); err != nil {
+		fmt.Printf("ERROR: File \"%s\" is not a valid scene file: %v\n", inputFile, err)
+		os.Exit(1)
+	}
+
+	if archive.Version > sceneArchiveVersion {
+		fmt.Printf("ERROR: Scene file version %d is newer than supported version %d\n", archive.Version, sceneArchiveVersion)
+		os.Exit(1)
+	}
+
+	loadGroups()
+	existing := loadScenes()
+
+	var unmatched []string
+	imported := 0
+
+	for _, definition := range archive.Scenes {
+		if len(findScenes(existing, definition.Name)) > 0 {
+			fmt.Printf("WARN: Scene \"%s\" already exists, skipping\n", definition.Name)
+			continue
+		}
+
+		newscene, missing, ok := definitionToScene(definition)
+		for _, eachmissing := range missing {
+			unmatched = append(unmatched, fmt.Sprintf("%s: %s", definition.Name, eachmissing))
+		}
+		if !ok {
+			continue
+		}
+
+		resp, err := myBridge.CreateScene(newscene)
+		if err != nil {
+			fmt.Printf("ERROR: Could not create scene \"%s\": %v\n", definition.Name, err)
+			continue
+		}
+
+		fmt.Printf("Created scene \"%s\" with id %v\n", definition.Name, resp.Success["id"])
+		imported++
+	}
+
+	fmt.Printf("\nImported %d of %d scenes\n", imported, len(archive.Scenes))
+
+	if len(unmatched) > 0 {
+		fmt.Println("\nCould not match:")
+		for _, eachunmatched := range unmatched {
+			fmt.Printf("  %s\n", eachunmatched)
+		}
+	}
+}
+
+// converts a portable scene definition into a scene for this bridge, returning anything that could not be matched
+func definitionToScene(definition sceneDefinition) (*huego.Scene, []string, bool) {
+	var missing []string
+
+	newscene := &huego.Scene{
+		Name:           definition.Name,
+		Type:           definition.Type,
+		TransitionTime: definition.TransitionTime,
+		LightStates:    map[int]huego.State{},
+	}
+
+	if newscene.Type == "" {
+		newscene.Type = "LightScene"
+	}
+
+	if newscene.Type == "GroupScene" {
+		groupid, found := getGroupIDFromName(definition.Group)
+		if !found {
+			missing = append(missing, fmt.Sprintf("group \"%s\"", definition.Group))
+			return nil, missing, false
+		}
+		newscene.Group = strconv.Itoa(groupid)
+	}
+
+	for _, eachlight := range definition.Lights {
+		var lightid int
+		if light, found := getLightFromUniqueID(eachlight.UniqueID); found {
+			lightid = light.ID
+		} else if eachlight.UniqueID == "" {
+			// fall back to the name only when no uniqueid was exported
+			getid, foundLightID := getLightIDFromName(eachlight.Name)
+			if !foundLightID {
+				missing = append(missing, fmt.Sprintf("light \"%s\"", eachlight.Name))
+				continue
+			}
+			lightid = getid
+		} else {
+			missing = append(missing, fmt.Sprintf("light \"%s\" (%s)", eachlight.Name, eachlight.UniqueID))
+			continue
+		}
+
+		// group scenes take their lights from the group
+		if newscene.Type != "GroupScene" {
+			newscene.Lights = append(newscene.Lights, strconv.Itoa(lightid))
+		}
+
+		newscene.LightStates[lightid] = huego.State{
+			On:             eachlight.State.On,
+			Bri:            eachlight.State.Bri,
+			Hue:            eachlight.State.Hue,
+			Sat:            eachlight.State.Sat,
+			Xy:             eachlight.State.Xy,
+			Ct:             eachlight.State.Ct,
+			Effect:         eachlight.State.Effect,
+			TransitionTime: eachlight.State.TransitionTime,
+		}
+	}
+
+	if len(newscene.LightStates) < 1 {
+		missing = append(missing, "no lights could be matched, scene skipped")
+		return nil, missing, false
+	}
+
+	return newscene, missing, true
+}
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amimof/huego"
+	"github.com/spf13/viper"
+)
+
+// bitmask of days used by the bridge for recurring times, 0MTWTFSS
+var scheduleDays = map[string]int{
+	"monday":    64,
+	"tuesday":   32,
+	"wednesday": 16,
+	"thursday":  8,
+	"friday":    4,
+	"saturday":  2,
+	"sunday":    1,
+}
+
+var scheduleDayGroups = map[string]int{
+	"day":      127,
+	"days":     127,
+	"weekday":  124,
+	"weekdays": 124,
+	"weekend":  3,
+	"weekends": 3,
+}
+
+// time patterns understood by the bridge which are passed through untouched
+var bridgeTimePattern = regexp.MustCompile(`^(W\d{1,3}/T\d{2}:\d{2}:\d{2}(/T\d{2}:\d{2}:\d{2})?|R(\d{2})?/PT\d{2}:\d{2}:\d{2}|PT\d{2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2})$`)
+
+// loads all schedules from the bridge sorted by ID
+func loadSchedules() []*huego.Schedule {
+	schedules, err := myBridge.GetSchedules()
+	if err != nil {
+		fmt.Println("ERROR: Could not load schedules from bridge")
+		os.Exit(1)
+	}
+
+	sort.SliceStable(schedules, func(i, j int) bool {
+		return schedules[i].ID < schedules[j].ID
+	})
+
+	return schedules
+}
+
+// find a schedule when given its name or ID
+func findSchedule(schedules []*huego.Schedule, selector string) (*huego.Schedule, bool) {
+	if scheduleid, err := strconv.Atoi(selector); err == nil {
+		for _, eachschedule := range schedules {
+			if eachschedule.ID == scheduleid {
+				return eachschedule, true
+			}
+		}
+		return nil, false
+	}
+
+	for _, eachschedule := range schedules {
+		if strings.EqualFold(eachschedule.Name, selector) {
+			return eachschedule, true
+		}
+	}
+	return nil, false
+}
+
+// display a list of all schedules
+func listSchedules() {
+	loadGroups()
+	schedules := loadSchedules()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "ID", "Name", "Status", "Time", "Command")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "--", "----", "------", "----", "-------")
+
+	for _, eachschedule := range schedules {
+		scheduletime := eachschedule.LocalTime
+		if scheduletime == "" {
+			scheduletime = eachschedule.Time
+		}
+
+		command := ""
+		if eachschedule.Command != nil {
+			command = describeAction(eachschedule.Command.Address, eachschedule.Command.Body)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t\n", eachschedule.ID, eachschedule.Name, eachschedule.Status, describeScheduleTime(scheduletime), command)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of schedules found: %d\n", len(schedules))
+}
+
+// translates a friendly time into a bridge time pattern
+//
+//	in 30m                      -> PT00:30:00
+//	every 10m                   -> R/PT00:10:00
+//	every weekday at 07:00      -> W124/T07:00:00
+//	every monday,friday at 7:30 -> W68/T07:30:00
+//	2026-12-24T18:00            -> 2026-12-24T18:00:00
+func parseScheduleTime(input string, now time.Time) (string, error) {
+	input = strings.TrimSpace(input)
+
+	if bridgeTimePattern.MatchString(input) {
+		return input, nil
+	}
+
+	lower := strings.ToLower(input)
+
+	if strings.HasPrefix(lower, "in ") {
+		timer, err := formatTimer(strings.TrimPrefix(lower, "in "))
+		if err != nil {
+			return "", err
+		}
+		return timer, nil
+	}
+
+	if strings.HasPrefix(lower, "every ") {
+		rest := strings.TrimPrefix(lower, "every ")
+
+		if !strings.Contains(rest, " at ") {
+			timer, err := formatTimer(rest)
+			if err != nil {
+				return "", fmt.Errorf("\"%s\" needs a duration like \"every 10m\" or days and a time like \"every weekday at 07:00\"", input)
+			}
+			return "R/" + timer, nil
+		}
+
+		parts := strings.SplitN(rest, " at ", 2)
+		days, err := parseScheduleDays(parts[0])
+		if err != nil {
+			return "", err
+		}
+
+		clock, err := parseClock(parts[1])
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("W%d/T%s", days, clock), nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+		absolute, err := time.ParseInLocation(layout, input, now.Location())
+		if err != nil {
+			continue
+		}
+		if absolute.Before(now) {
+			return "", fmt.Errorf("\"%s\" is in the past", input)
+		}
+		return absolute.Format("2006-01-02T15:04:05"), nil
+	}
+
+	return "", fmt.Errorf("\"%s\" is not a recognised time, try \"in 30m\", \"every weekday at 07:00\" or \"2026-12-24T18:00\"", input)
+}
+
+// formats a duration such as 1h30m as a bridge timer PT01:30:00
+func formatTimer(input string) (string, error) {
+	duration, err := time.ParseDuration(strings.ReplaceAll(input, " ", ""))
+	if err != nil {
+		return "", fmt.Errorf("\"%s\" is not a valid duration", input)
+	}
+
+	if duration < time.Second || duration >= 24*time.Hour {
+		return "", fmt.Errorf("\"%s\" must be between 1s and 24h", input)
+	}
+
+	seconds := int(duration.Seconds())
+	return fmt.Sprintf("PT%02d:%02d:%02d", seconds/3600, (seconds/60)%60, seconds%60), nil
+}
+
+// converts a list of days such as "monday,wednesday" or "weekday" in to a bridge day bitmask
+func parseScheduleDays(input string) (int, error) {
+	if days, ok := scheduleDayGroups[strings.TrimSpace(input)]; ok {
+		return days, nil
+	}
+
+	days := 0
+	for _, eachday := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if eachday == "and" {
+			continue
+		}
+
+		found := false
+		for name, bit := range scheduleDays {
+			if len(eachday) >= 3 && strings.HasPrefix(name, eachday) {
+				days |= bit
+				found = true
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("\"%s\" is not a day, try \"day\", \"weekday\", \"weekend\" or day names", eachday)
+		}
+	}
+
+	if days == 0 {
+		return 0, fmt.Errorf("no days given")
+	}
+
+	return days, nil
+}
+
+// converts 7:00 or 07:00:30 to 07:00:00 or 07:00:30
+func parseClock(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if clock, err := time.Parse(layout, input); err == nil {
+			return clock.Format("15:04:05"), nil
+		}
+	}
+	return "", fmt.Errorf("\"%s\" is not a valid time, use HH:MM", input)
+}
+
+// translates a bridge time pattern in to a readable description
+func describeScheduleTime(pattern string) string {
+	switch {
+	case strings.HasPrefix(pattern, "W"):
+		parts := strings.SplitN(strings.TrimPrefix(pattern, "W"), "/T", 2)
+		bits, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) < 2 {
+			return pattern
+		}
+		return fmt.Sprintf("every %s at %s", describeScheduleDays(bits), parts[1])
+
+	case strings.HasPrefix(pattern, "R"):
+		parts := strings.SplitN(pattern, "/", 2)
+		if len(parts) < 2 {
+			return pattern
+		}
+		return "every " + describeTimer(parts[1])
+
+	case strings.HasPrefix(pattern, "PT"):
+		return "in " + describeTimer(pattern)
+	}
+
+	return pattern
+}
+
+// translates a day bitmask in to a readable list of days
+func describeScheduleDays(bits int) string {
+	switch bits {
+	case 127:
+		return "day"
+	case 124:
+		return "weekday"
+	case 3:
+		return "weekend"
+	}
+
+	var days []string
+	for _, name := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"} {
+		if bits&scheduleDays[name] != 0 {
+			days = append(days, name)
+		}
+	}
+	return strings.Join(days, ",")
+}
+
+// translates PT01:30:00 in to 1h30m0s
+func describeTimer(timer string) string {
+	var hours, minutes, seconds int
+	if _, err := fmt.Sscanf(timer, "PT%d:%d:%d", &hours, &minutes, &seconds); err != nil {
+		return timer
+	}
+	return (time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second).String()
+}
+
+// builds the command a schedule runs from the --light, --group or --scene and --action arguments
+func buildTargetCommand() (*huego.Command, error) {
+	command := &huego.Command{Method: "PUT"}
+
+	switch {
+	case viper.IsSet("scene"):
+		scenes := findScenes(loadScenes(), viper.GetString("scene"))
+		if len(scenes) != 1 {
+			return nil, fmt.Errorf("\"--scene %s\" must match exactly one scene, found %d", viper.GetString("scene"), len(scenes))
+		}
+
+		// light scenes are recalled through group 0, which contains all lights
+		groupid := "0"
+		if scenes[0].Type == "GroupScene" {
+			groupid = scenes[0].Group
+		}
+
+		command.Address = fmt.Sprintf("/api/%s/groups/%s/action", myBridge.User, groupid)
+		command.Body = map[string]interface{}{"scene": scenes[0].ID}
+		return command, nil
+
+	case viper.IsSet("group"):
+		groupid, found := getGroupIDFromName(viper.GetString("group"))
+		if !found {
+			return nil, fmt.Errorf("\"--group %s\" is not a valid group name or group id", viper.GetString("group"))
+		}
+		command.Address = fmt.Sprintf("/api/%s/groups/%d/action", myBridge.User, groupid)
+
+	case viper.IsSet("light"):
+		if !checkLightValid(lightID) {
+			return nil, fmt.Errorf("\"--light %s\" is not a valid light name or light id", viper.GetString("light"))
+		}
+		command.Address = fmt.Sprintf("/api/%s/lights/%d/state", myBridge.User, lightID)
+
+	default:
+		return nil, fmt.Errorf("one of --light, --group or --scene must be given")
+	}
+
+	switch action {
+	case "on":
+		command.Body = map[string]interface{}{"on": true}
+	case "off":
+		command.Body = map[string]interface{}{"on": false}
+	default:
+		return nil, fmt.Errorf("\"--action on\" or \"--action off\" must be given with --light or --group")
+	}
+
+	return command, nil
+}
+
+// creates a schedule on the bridge
+func createSchedule(name string, friendlyTime string) {
+	loadGroups()
+
+	if friendlyTime == "" {
+		fmt.Println("ERROR: --time must be given when creating a schedule")
+		os.Exit(1)
+	}
+
+	localtime, err := parseScheduleTime(friendlyTime, time.Now())
+	if err != nil {
+		fmt.Printf("ERROR: --time %v\n", err)
+		os.Exit(1)
+	}
+
+	command, err := buildTargetCommand()
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	newschedule := &huego.Schedule{
+		Name:      name,
+		Command:   command,
+		LocalTime: localtime,
+	}
+
+	resp, err := myBridge.CreateSchedule(newschedule)
+	if err != nil {
+		fmt.Printf("ERROR: Could not create schedule \"%s\": %v\n", name, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Created schedule \"%s\" with id %v: %s, %s\n", name, resp.Success["id"], describeScheduleTime(localtime), describeAction(command.Address, command.Body))
+}
+
+// enables or disables a schedule
+func setScheduleStatus(selector string, enabled bool) {
+	schedule, found := findSchedule(loadSchedules(), selector)
+	if !found {
+		fmt.Printf("ERROR: \"%s\" is not a valid schedule name or schedule id\n", selector)
+		os.Exit(1)
+	}
+
+	status := "disabled"
+	if enabled {
+		status = "enabled"
+	}
+
+	// huego.UpdateSchedule sends every field so the status is set directly
+	_, err := bridgeRequest(myBridge, "PUT", fmt.Sprintf("schedules/%d", schedule.ID), map[string]interface{}{"status": status})
+	if err != nil {
+		fmt.Printf("ERROR: Could not update schedule \"%s\": %v\n", schedule.Name, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Schedule \"%s\" is now %s\n", schedule.Name, status)
+}
+
+// deletes a schedule
+func deleteSchedule(selector string) {
+	schedule, found := findSchedule(loadSchedules(), selector)
+	if !found {
+		fmt.Printf("ERROR: \"%s\" is not a valid schedule name or schedule id\n", selector)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Delete schedule \"%s\" (%d)? [y/n]: ", schedule.Name, schedule.ID)
+	if !yesNoPrompt() {
+		fmt.Println("WARN: Aborting schedule delete")
+		return
+	}
+
+	checkErr(myBridge.DeleteSchedule(schedule.ID))
+	fmt.Printf("Deleted schedule \"%s\"\n", schedule.Name)
+}
+
+// describes a bridge api address and body in readable terms, resolving IDs to names
+func describeAction(address string, body interface{}) string {
+	description := describeAddress(address)
+
+	if fields, ok := body.(map[string]interface{}); ok {
+		var keys []string
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			switch {
+			case k == "on" && fields[k] == true:
+				description += " on"
+			case k == "on" && fields[k] == false:
+				description += " off"
+			case k == "scene":
+				description += fmt.Sprintf(" scene %s", describeScene(fmt.Sprint(fields[k])))
+			default:
+				description += fmt.Sprintf(" %s=%v", k, fields[k])
+			}
+		}
+	}
+
+	return description
+}
+
+// describes a bridge api address such as /api/<user>/lights/3/state as light "Kitchen"
+func describeAddress(address string) string {
+	parts := strings.Split(strings.Trim(address, "/"), "/")
+
+	// strip the /api/<username> prefix used by schedules
+	if len(parts) > 2 && parts[0] == "api" {
+		parts = parts[2:]
+	}
+
+	if len(parts) < 2 {
+		return address
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return address
+	}
+
+	name := ""
+	switch parts[0] {
+	case "lights":
+		name = getLightName(id)
+	case "groups":
+		name = getGroupName(id)
+		if id == 0 {
+			name = "all lights"
+		}
+	case "sensors":
+		if sensor, found := getSensor(id); found {
+			name = sensor.Name
+		}
+	default:
+		return address
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("%d (missing)", id)
+	}
+
+	return fmt.Sprintf("%s \"%s\"", strings.TrimSuffix(parts[0], "s"), name)
+}
+
+// returns the name of a scene, or its ID if it cannot be loaded
+func describeScene(sceneID string) string {
+	scene, err := myBridge.GetScene(sceneID)
+	if err != nil || scene.Name == "" {
+		return sceneID
+	}
+	return fmt.Sprintf("\"%s\"", scene.Name)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleTime(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  string
+		err   string
+	}{
+		{input: "in 30m", want: "PT00:30:00"},
+		{input: "in 1h30m", want: "PT01:30:00"},
+		{input: "every 10m", want: "R/PT00:10:00"},
+		{input: "every weekday at 07:00", want: "W124/T07:00:00"},
+		{input: "every monday,friday at 7:30", want: "W68/T07:30:00"},
+		{input: "every mon,fri at 18:30", want: "W68/T18:30:00"},
+		{input: "Every Weekend at 09:15:30", want: "W3/T09:15:30"},
+		{input: "  in 5m  ", want: "PT00:05:00"},
+		// bridge patterns are passed through untouched
+		{input: "W124/T07:00:00", want: "W124/T07:00:00"},
+		{input: "W127/T22:00:00/T06:00:00", want: "W127/T22:00:00/T06:00:00"},
+		{input: "R/PT00:10:00", want: "R/PT00:10:00"},
+		{input: "R05/PT00:10:00", want: "R05/PT00:10:00"},
+		{input: "PT00:00:30", want: "PT00:00:30"},
+		{input: "2026-12-24T18:00:00", want: "2026-12-24T18:00:00"},
+		{input: "2026-12-24T18:00", want: "2026-12-24T18:00:00"},
+		{input: "2026-12-24 18:00:05", want: "2026-12-24T18:00:05"},
+		{input: "in soon", err: `"soon" is not a valid duration`},
+		{input: "in 25h", err: `"25h" must be between 1s and 24h`},
+		{input: "every fortnight", err: `"every fortnight" needs a duration like "every 10m" or days and a time like "every weekday at 07:00"`},
+		{input: "every moonday at 07:00", err: `"moonday" is not a day, try "day", "weekday", "weekend" or day names`},
+		{input: "every weekday at 25:00", err: `"25:00" is not a valid time, use HH:MM`},
+		{input: "2020-01-01T10:00", err: `"2020-01-01T10:00" is in the past`},
+		{input: "tomorrow", err: `"tomorrow" is not a recognised time, try "in 30m", "every weekday at 07:00" or "2026-12-24T18:00"`},
+	}
+
+	for _, tt := range tests {
+		got, err := parseScheduleTime(tt.input, now)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parseScheduleTime(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseScheduleTime(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseScheduleTime(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseScheduleDays(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+		err   string
+	}{
+		{input: "day", want: 127},
+		{input: "weekdays", want: 124},
+		{input: "weekends", want: 3},
+		{input: "sunday", want: 1},
+		{input: "mon,wed", want: 80},
+		{input: "tue and thu", want: 40},
+		{input: "sat sun", want: 3},
+		{input: "mo", err: `"mo" is not a day, try "day", "weekday", "weekend" or day names`},
+		{input: "funday", err: `"funday" is not a day, try "day", "weekday", "weekend" or day names`},
+		{input: "", err: "no days given"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseScheduleDays(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parseScheduleDays(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseScheduleDays(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseScheduleDays(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   string
+	}{
+		{input: "7:00", want: "07:00:00"},
+		{input: "07:00:30", want: "07:00:30"},
+		{input: " 18:45 ", want: "18:45:00"},
+		{input: "7am", err: `"7am" is not a valid time, use HH:MM`},
+		{input: "24:00", err: `"24:00" is not a valid time, use HH:MM`},
+	}
+
+	for _, tt := range tests {
+		got, err := parseClock(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parseClock(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseClock(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseClock(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTimer(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   string
+	}{
+		{input: "90s", want: "PT00:01:30"},
+		{input: "1h 30m", want: "PT01:30:00"},
+		{input: "23h59m59s", want: "PT23:59:59"},
+		{input: "1s", want: "PT00:00:01"},
+		{input: "24h", err: `"24h" must be between 1s and 24h`},
+		{input: "500ms", err: `"500ms" must be between 1s and 24h`},
+		{input: "abc", err: `"abc" is not a valid duration`},
+	}
+
+	for _, tt := range tests {
+		got, err := formatTimer(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("formatTimer(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("formatTimer(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("formatTimer(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeScheduleTime(t *testing.T) {
+	tests := map[string]string{
+		"W124/T07:00:00":      "every weekday at 07:00:00",
+		"W68/T18:30:00":       "every monday,friday at 18:30:00",
+		"R/PT00:10:00":        "every 10m0s",
+		"PT01:30:00":          "in 1h30m0s",
+		"2026-12-24T18:00:00": "2026-12-24T18:00:00",
+	}
+
+	for pattern, want := range tests {
+		if got := describeScheduleTime(pattern); got != want {
+			t.Errorf("describeScheduleTime(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// the environment variable the username is read from when nothing else provides it
+const usernameEnvDefault string = "HUELIGHTS_USERNAME"
+
+// the longest a username command may run, password managers can wait for an unlock
+const usernameCommandTimeout time.Duration = 30 * time.Second
+
+// config keys which hold secrets and are masked when displayed
+var secretKeys = map[string]bool{
+	"username":  true,
+	"clientkey": true,
+}
+
+// does a profile keep its username outside the config file
+func (p bridgeProfile) hasSecretSource() bool {
+	return p.UsernameEnv != "" || p.UsernameFile != "" || p.UsernameCommand != ""
+}
+
+// reads a username from a secrets file, which must only be readable by its owner,
+// the line after the username holds its client key when one was generated
+func readSecretFile(secretFile string) (string, error) {
+	info, err := os.Stat(secretFile)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("secrets file \"%s\" does not exist, create a username with --createuser", secretFile)
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("secrets file \"%s\" has permissions %04o, it must be 0600", secretFile, info.Mode().Perm())
+	}
+
+	data, err := ioutil.ReadFile(secretFile)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0]), nil
+}
+
+// writes a username and any client key to a secrets file only readable by its owner
+func writeSecretFile(secretFile string, username string, clientKey string) error {
+	data := username + "\n"
+	if clientKey != "" {
+		data += clientKey + "\n"
+	}
+	if err := ioutil.WriteFile(secretFile, []byte(data), 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of an existing file
+	return os.Chmod(secretFile, 0600)
+}
+
+// reads a username from the output of a command, such as a password manager
+func readSecretCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), usernameCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("username command failed: %v", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// returns the username of a profile from the config, its environment variable, secrets file or command, in that order
+func resolveUsername(profile bridgeProfile) (string, error) {
+	if profile.Username != "" {
+		return profile.Username, nil
+	}
+
+	if profile.UsernameEnv != "" {
+		if username := os.Getenv(profile.UsernameEnv); username != "" {
+			return username, nil
+		}
+		if profile.UsernameFile == "" && profile.UsernameCommand == "" {
+			return "", fmt.Errorf("environment variable %s is not set", profile.UsernameEnv)
+		}
+	}
+
+	if profile.UsernameFile != "" {
+		return readSecretFile(profile.UsernameFile)
+	}
+
+	if profile.UsernameCommand != "" {
+		return readSecretCommand(profile.UsernameCommand)
+	}
+
+	return os.Getenv(usernameEnvDefault), nil
+}
+
+// returns the profile in use from the config, after selectProfile has applied it
+func currentProfile() bridgeProfile {
+	return bridgeProfile{
+		Username:        viper.GetString("username"),
+		UsernameEnv:     viper.GetString("usernameenv"),
+		UsernameFile:    viper.GetString("usernamefile"),
+		UsernameCommand: viper.GetString("usernamecommand"),
+	}
+}
+
+// checks a generated client key has somewhere to be saved, as the bridge never shows it again,
+// a username read from an environment variable or command has nowhere for its client key
+func checkClientKeyDestination() error {
+	profile := currentProfile()
+	if profile.UsernameFile == "" && (profile.UsernameEnv != "" || profile.UsernameCommand != "") {
+		return fmt.Errorf("--generateclientkey has nowhere to save the client key, which the bridge cannot show again, set usernamefile in the config or leave out --generateclientkey")
+	}
+	return nil
+}
+
+// fills in the username from its secret source unless --username was passed, creating or rotating a user
+// needs no username, so a missing or unreadable source is only an error for other commands
+func loadUsername() error {
+	if pflag.CommandLine.Changed("username") {
+		return nil
+	}
+
+	username, err := resolveUsername(currentProfile())
+	if err != nil && !viper.IsSet("createuser") && !viper.IsSet("rotateuser") {
+		return err
+	}
+
+	viper.Set("username", username)
+	return nil
+}
+
+// masks a secret such as a username for display, unless --show-secrets was passed
+func maskSecret(secret string) string {
+	if secret == "" || viper.GetBool("show-secrets") {
+		return secret
+	}
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return secret[:4] + strings.Repeat("*", 8)
+}
+
+// masks secrets anywhere in nested settings, as displayed by --displayconfig
+func maskSettings(settings map[string]interface{}) map[string]interface{} {
+	masked := map[string]interface{}{}
+	for k, v := range settings {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			masked[k] = maskSettings(value)
+		case string:
+			if secretKeys[strings.ToLower(k)] {
+				masked[k] = maskSecret(value)
+				continue
+			}
+			masked[k] = value
+		default:
+			masked[k] = value
+		}
+	}
+	return masked
+}
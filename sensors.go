@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/amimof/huego"
+)
+
+var loadedSensors []huego.Sensor
+
+// loads sensors from the bridge preventing multiple uneccessary calls to bridge
+func loadSensors() {
+	sensors, err := myBridge.GetSensors()
+	if err != nil {
+		fmt.Println("ERROR: Could not load sensors from bridge")
+		os.Exit(1)
+	}
+
+	// sorting sensors by ID
+	sort.SliceStable(sensors, func(i, j int) bool {
+		return sensors[i].ID < sensors[j].ID
+	})
+
+	loadedSensors = sensors
+}
+
+// find a sensorID when given the name or ID of a sensor
+func getSensorIDFromName(sensorName string) (int, bool) {
+	if sensorid, err := strconv.Atoi(sensorName); err == nil {
+		for _, eachsensor := range loadedSensors {
+			if eachsensor.ID == sensorid {
+				return sensorid, true
+			}
+		}
+		return 0, false
+	}
+
+	for _, eachsensor := range loadedSensors {
+		if strings.EqualFold(eachsensor.Name, sensorName) {
+			return eachsensor.ID, true
+		}
+	}
+	return 0, false
+}
+
+// find a sensor when given its ID
+func getSensor(sensorID int) (huego.Sensor, bool) {
+	for _, eachsensor := range loadedSensors {
+		if eachsensor.ID == sensorID {
+			return eachsensor, true
+		}
+	}
+	return huego.Sensor{}, false
+}
+
+// find the first sensor of a type, such as the built in Daylight sensor
+func getSensorByType(sensorType string) (huego.Sensor, bool) {
+	for _, eachsensor := range loadedSensors {
+		if eachsensor.Type == sensorType {
+			return eachsensor, true
+		}
+	}
+	return huego.Sensor{}, false
+}
+
+// returns the physical device part of a sensor uniqueid, 00:17:88:01:02:00:af:28-02-0406 becomes 00:17:88:01:02:00:af:28
+func sensorDeviceID(uniqueID string) string {
+	return strings.SplitN(uniqueID, "-", 2)[0]
+}
+
+// returns the battery percentage of a sensor, false when the sensor has no battery
+func sensorBattery(sensor huego.Sensor) (int, bool) {
+	battery, ok := sensor.Config["battery"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(battery), true
+}
+
+// returns whether a sensor is reachable, sensors without a reachable setting are always reachable
+func sensorReachable(sensor huego.Sensor) bool {
+	reachable, ok := sensor.Config["reachable"].(bool)
+	if !ok {
+		return true
+	}
+	return reachable
+}
+
+// describes the state of a sensor in readable terms
+func describeSensorState(sensor huego.Sensor) string {
+	switch sensor.Type {
+	case "ZLLPresence", "CLIPPresence":
+		if presence, ok := sensor.State["presence"].(bool); ok {
+			if presence {
+				return "presence"
+			}
+			return "no presence"
+		}
+
+	case "ZLLTemperature", "CLIPTemperature":
+		if temperature, ok := sensor.State["temperature"].(float64); ok {
+			return fmt.Sprintf("%.2f °C", temperature/100)
+		}
+
+	case "ZLLLightLevel", "CLIPLightLevel":
+		if lightlevel, ok := sensor.State["lightlevel"].(float64); ok {
+			description := fmt.Sprintf("%.0f lux", lightLevelToLux(lightlevel))
+			if dark, ok := sensor.State["dark"].(bool); ok && dark {
+				description += ", dark"
+			}
+			if daylight, ok := sensor.State["daylight"].(bool); ok && daylight {
+				description += ", daylight"
+			}
+			return description
+		}
+
+	case "ZLLSwitch", "ZGPSwitch", "CLIPSwitch":
+		if buttonevent, ok := sensor.State["buttonevent"].(float64); ok {
+			return describeButtonEvent(int(buttonevent))
+		}
+		return "no button event"
+
+	case "Daylight":
+		if daylight, ok := sensor.State["daylight"].(bool); ok {
+			if daylight {
+				return "daylight"
+			}
+			return "dark"
+		}
+		return "not configured"
+
+	case "CLIPGenericFlag":
+		if flag, ok := sensor.State["flag"].(bool); ok {
+			return fmt.Sprintf("flag %t", flag)
+		}
+
+	case "CLIPGenericStatus":
+		if status, ok := sensor.State["status"].(float64); ok {
+			return fmt.Sprintf("status %d", int(status))
+		}
+	}
+
+	var values []string
+	for _, k := range sortedKeys(sensor.State) {
+		if k == "lastupdated" {
+			continue
+		}
+		values = append(values, fmt.Sprintf("%s=%v", k, sensor.State[k]))
+	}
+	return strings.Join(values, " ")
+}
+
+// converts a hue lightlevel, 10000 * log10(lux) + 1, in to lux
+func lightLevelToLux(lightlevel float64) float64 {
+	return math.Pow(10, (lightlevel-1)/10000)
+}
+
+// converts lux in to a hue lightlevel
+func luxToLightLevel(lux float64) int {
+	if lux <= 0 {
+		return 0
+	}
+	return int(math.Round(10000*math.Log10(lux) + 1))
+}
+
+// describes a switch buttonevent such as 1002 as button 1 short release
+func describeButtonEvent(buttonevent int) string {
+	events := map[int]string{
+		0: "initial press",
+		1: "hold",
+		2: "short release",
+		3: "long release",
+	}
+
+	event, ok := events[buttonevent%1000]
+	if !ok {
+		return fmt.Sprintf("event %d", buttonevent)
+	}
+	return fmt.Sprintf("button %d %s", buttonevent/1000, event)
+}
+
+// display a list of all sensors
+func listSensors() {
+	loadSensors()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", "ID", "Name", "Type", "Value", "Battery", "Reachable", "LastUpdated")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n", "--", "----", "----", "-----", "-------", "---------", "-----------")
+
+	for _, eachsensor := range loadedSensors {
+		battery := ""
+		if percent, ok := sensorBattery(eachsensor); ok {
+			battery = fmt.Sprintf("%d%%", percent)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%t\t%v\t\n", eachsensor.ID, eachsensor.Name, eachsensor.Type, describeSensorState(eachsensor), battery, sensorReachable(eachsensor), eachsensor.State["lastupdated"])
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of sensors found: %d\n", len(loadedSensors))
+}
+
+// display sensors with a battery at or below the threshold, returns true if any were found
+func displayLowBattery(threshold int) bool {
+	loadSensors()
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "ID", "Name", "Type", "Battery", "UniqueID")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "--", "----", "----", "-------", "--------")
+
+	// a physical device such as a motion sensor appears as several sensors, so only report it once
+	reported := map[string]bool{}
+	found := 0
+
+	for _, eachsensor := range loadedSensors {
+		percent, ok := sensorBattery(eachsensor)
+		if !ok || percent > threshold {
+			continue
+		}
+
+		device := sensorDeviceID(eachsensor.UniqueID)
+		if device != "" && reported[device] {
+			continue
+		}
+		reported[device] = true
+		found++
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d%%\t%s\t\n", eachsensor.ID, eachsensor.Name, eachsensor.Type, percent, eachsensor.UniqueID)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of devices with battery at or below %d%%: %d\n", threshold, found)
+
+	return found > 0
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amimof/huego"
+)
+
+// where whitelist users are deleted, as the local api cannot delete them
+const accountAppsURL string = "https://account.meethue.com/apps"
+
+// the format of whitelist create and last use dates, which are in UTC
+const whitelistDateFormat string = "2006-01-02T15:04:05"
+
+// a whitelist user flagged by the user audit
+type userProblem struct {
+	User     huego.Whitelist
+	Problems []string
+}
+
+// returns when a whitelist user was last used, or created if it has never been used
+func userLastUsed(user huego.Whitelist) (time.Time, bool) {
+	for _, eachdate := range []string{user.LastUseDate, user.CreateDate} {
+		if used, err := time.Parse(whitelistDateFormat, eachdate); err == nil {
+			return used, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// is a whitelist user one this tool created
+func isOwnUser(user huego.Whitelist) bool {
+	for _, eachapplication := range []string{deviceTypeApplication, applicationName} {
+		if strings.HasPrefix(strings.ToLower(user.Name), eachapplication+"#") {
+			return true
+		}
+	}
+	return false
+}
+
+// finds users unused for staleDays and duplicate devicetypes, marking old credentials of this tool among them,
+// never flagging the user logged in
+func auditUsers(staleDays int, now time.Time) []userProblem {
+	allusers, err := myBridge.GetUsers()
+	checkErr(err)
+
+	// the most recently used of each devicetype is kept, the rest are duplicates
+	sort.SliceStable(allusers, func(i, j int) bool {
+		usedi, _ := userLastUsed(allusers[i])
+		usedj, _ := userLastUsed(allusers[j])
+		return usedi.After(usedj)
+	})
+
+	seen := map[string]bool{}
+	var problems []userProblem
+
+	for _, eachuser := range allusers {
+		devicetype := strings.ToLower(eachuser.Name)
+		duplicate := seen[devicetype]
+		seen[devicetype] = true
+
+		if eachuser.Username == myBridge.User {
+			continue
+		}
+
+		var found []string
+		if used, ok := userLastUsed(eachuser); ok && now.Sub(used) > time.Duration(staleDays)*24*time.Hour {
+			found = append(found, fmt.Sprintf("unused for %d days", int(now.Sub(used).Hours()/24)))
+		}
+		if duplicate {
+			found = append(found, "duplicate devicetype")
+		}
+		// every machine has its own huelights user, which is only old once it is stale or replaced by a newer one
+		if len(found) > 0 && isOwnUser(eachuser) {
+			found = append(found, "old "+deviceTypeApplication+" credential")
+		}
+
+		if len(found) > 0 {
+			problems = append(problems, userProblem{User: eachuser, Problems: found})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return strings.ToLower(problems[i].User.Name) < strings.ToLower(problems[j].User.Name)
+	})
+
+	return problems
+}
+
+// display the results of a user audit with a checklist for deleting the flagged users from the Hue account
+func displayUserAudit(staleDays int) {
+	problems := auditUsers(staleDays, time.Now().UTC())
+
+	if len(problems) < 1 {
+		fmt.Printf("No stale users found, none unused for %d days or duplicated\n", staleDays)
+		return
+	}
+
+	const padding = 1
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, padding, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "Name", "Username", "CreateDate", "LastUseDate", "Problem")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", "----", "--------", "----------", "-----------", "-------")
+
+	for _, eachproblem := range problems {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", eachproblem.User.Name, maskSecret(eachproblem.User.Username), eachproblem.User.CreateDate, eachproblem.User.LastUseDate, strings.Join(eachproblem.Problems, ", "))
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nNumber of users flagged: %d\n", len(problems))
+
+	fmt.Printf("\nUsers cannot be deleted through the bridge, sign in at %s and remove:\n", accountAppsURL)
+	for _, eachproblem := range problems {
+		fmt.Printf("  [ ] %s, last used %s\n", eachproblem.User.Name, eachproblem.User.LastUseDate)
+	}
+}